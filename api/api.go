@@ -17,12 +17,16 @@ import (
  */
 
 type TreeRequest struct {
-	Data []string `json:"data"`
-	Name string   `json:"name"`
+	Data       []string `json:"data"`
+	Name       string   `json:"name"`
+	Hash       string   `json:"hash"`
+	HashLength int      `json:"hashLength"`
+	Tenant     string   `json:"tenant"`
 }
 type ProofRequest struct {
-	Data string `json:"data"`
-	Name string `json:"name"`
+	Data  string                  `json:"data"`
+	Name  string                  `json:"name"`
+	Proof *merkletree.MerkleProof `json:"proof"`
 }
 type UpdateLeafReq struct {
 	Data  string `json:"data"`
@@ -40,14 +44,15 @@ func byteArray(req TreeRequest) [][]byte {
 	return data
 }
 
-var hashing = hash.NewBlake3()
+// defaultHash is the hash type of trees created without one.
+const defaultHash = "blake3"
 
 // @Summary Create a new Merkle tree
 // @Description Creates a new Merkle tree with the given data
 // @Tags Merkle trees
 // @Accept  json
 // @Produce  json
-// @Param tree body TreeRequest true "The data and name for the new Merkle tree"
+// @Param tree body TreeRequest true "The data, name, optional hash type (blake3 by default), hash length and tenant for the new Merkle tree"
 // @Success 200 {string} string	""
 // @Failure 400 {object} ErrorResponse
 // @Router /create [post]
@@ -58,23 +63,90 @@ func CreateTree(c *gin.Context) {
 		return
 	}
 
+	if data.Hash == "" {
+		data.Hash = defaultHash
+	}
+	hashing, err := treeHash(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Create the tree
 	tree, err := merkletree.NewTree(byteArray(data), hashing)
 
 	if err != nil {
 		c.Error(err)
+	} else if err := saveTree(data.Name, &data, tree); err != nil {
+		c.Error(err)
 	} else {
 		trees[data.Name] = tree
 		c.JSON(http.StatusOK, "")
 	}
 }
 
+// treeHash returns the hash type of a new tree. A tree bound to a tenant is hashed with BLAKE3 keyed with the key of
+// the tenant, so that its root can't be computed or correlated with other trees without that key.
+// The digest length can only be changed from the default one of the hash type for an XOF such as BLAKE3.
+func treeHash(req TreeRequest) (hash.HashType, error) {
+	hashing, err := tenantHash(req)
+	if err != nil || req.HashLength == 0 {
+		return hashing, err
+	}
+	return hash.WithLength(hashing, req.HashLength)
+}
+
+// tenantHash returns the hash type of a new tree with its default digest length.
+func tenantHash(req TreeRequest) (hash.HashType, error) {
+	if req.Tenant == "" {
+		return hash.ByName(req.Hash)
+	}
+	if req.Hash != defaultHash {
+		return nil, fmt.Errorf("trees bound to a tenant are hashed with %s", defaultHash)
+	}
+	key, err := tenantKey(req.Tenant)
+	if err != nil {
+		return nil, err
+	}
+	return hash.NewBlake3Keyed(key), nil
+}
+
+// @Summary Generate a Merkle proof
+// @Description Generates the Merkle proof of a given data value, with the root of the tree and the hash of the leaf
+// @Tags Merkle trees
+// @Accept  json
+// @Produce  json
+// @Param proof body ProofRequest true "The data and name of the tree"
+// @Success 200 {object} merkletree.MerkleProof
+// @Failure 400 {object} ErrorResponse
+// @Router /proof [post]
+func GenerateProof(c *gin.Context) {
+	var data ProofRequest
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tree, ok := trees[data.Name]
+	if !ok {
+		c.Error(fmt.Errorf("no tree found %v", data.Name))
+		return
+	}
+	proof, err := tree.GenerateMProof([]byte(data.Data))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, proof)
+}
+
 // @Summary Verify a Merkle proof
-// @Description Verifies a Merkle proof for a given data value
+// @Description Verifies a Merkle proof for a given data value against the root of the tree. Without a proof in the
+// @Description request, the proof is generated by the tree.
 // @Tags Merkle trees
 // @Accept  json
 // @Produce  json
-// @Param proof body ProofRequest true "The data, name, and proof to verify"
+// @Param proof body ProofRequest true "The data, name, and optional proof to verify"
 // @Success 200 {object} VerificationResponse
 // @Failure 400 {object} ErrorResponse
 // @Router /verify [post]
@@ -90,9 +162,16 @@ func VerifyProof(c *gin.Context) {
 
 	if !ok {
 		c.Error(fmt.Errorf("no tree found %v", data.Name))
+	} else if data.Proof != nil {
+		verified, err := merkletree.VerifyMProof([]byte(data.Data), data.Proof, tree.MerkleRoot(), tree.HashType())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, VerificationResponse{Verified: verified, Proof: data.Proof})
 	} else {
-		_, verified, _ := verify(tree, []byte(data.Data))
-		c.JSON(http.StatusOK, gin.H{"verified": verified})
+		proof, verified, _ := verify(tree, []byte(data.Data))
+		c.JSON(http.StatusOK, VerificationResponse{Verified: verified, Proof: proof})
 	}
 }
 
@@ -142,7 +221,14 @@ func UpdateLeaf(c *gin.Context) {
 	if !ok {
 		c.Error(fmt.Errorf("no tree found  %v", data.Name))
 	} else {
-		tree.UpdateLeaf(data.Index, []byte(data.Data))
+		if err := tree.UpdateLeaf(data.Index, []byte(data.Data)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := saveTree(data.Name, nil, tree); err != nil {
+			c.Error(err)
+			return
+		}
 		c.JSON(http.StatusOK, "")
 	}
 }
@@ -154,7 +240,8 @@ type ErrorResponse struct {
 
 // VerificationResponse represents a response to a Merkle proof verification request
 type VerificationResponse struct {
-	Verified bool `json:"verified"`
+	Verified bool                    `json:"verified"`
+	Proof    *merkletree.MerkleProof `json:"proof,omitempty"`
 }
 
 func ErrorHandler(c *gin.Context) {
@@ -178,7 +265,7 @@ func verify(tree *merkletree.MerkleTree, data []byte) (*merkletree.MerkleProof,
 	}
 
 	// Verify the proof for 'Baz'
-	verified, err := merkletree.VerifyMProof(data, proof, root, hashing)
+	verified, err := merkletree.VerifyMProof(data, proof, root, tree.HashType())
 
 	return proof, verified, err
 }
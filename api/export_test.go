@@ -0,0 +1,9 @@
+package api
+
+import merkletree "github.com/reactivejson/merkleTree/internal/merkle"
+
+// ResetTrees forgets the trees in memory and the data directory, as a restart of the server does.
+func ResetTrees() {
+	trees = make(map[string]*merkletree.MerkleTree)
+	dataDir = ""
+}
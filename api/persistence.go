@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	merkletree "github.com/reactivejson/merkleTree/internal/merkle"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// The files of a saved tree: its snapshot, and the request telling which hash type reads it back.
+const (
+	snapshotExt = ".tree"
+	requestExt  = ".json"
+)
+
+// dataDir is the directory trees are saved to, or empty if they are kept in memory only.
+var dataDir string
+
+// LoadTrees restores the trees saved in dir, which is created if needed, and saves the trees created or updated from
+// then on to it. The tenant keys must be set first: the trees of a tenant are read back with its key.
+func LoadTrees(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+requestExt))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), requestExt)
+		tree, err := loadTree(dir, name)
+		if err != nil {
+			return fmt.Errorf("failed to restore tree %q: %w", name, err)
+		}
+		trees[name] = tree
+	}
+	dataDir = dir
+	return nil
+}
+
+func loadTree(dir, name string) (*merkletree.MerkleTree, error) {
+	encoded, err := os.ReadFile(filepath.Join(dir, name+requestExt))
+	if err != nil {
+		return nil, err
+	}
+	var req TreeRequest
+	if err := json.Unmarshal(encoded, &req); err != nil {
+		return nil, err
+	}
+	hashing, err := treeHash(req)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filepath.Join(dir, name+snapshotExt))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return merkletree.ReadTree(f, hashing)
+}
+
+// saveTree saves the snapshot of a tree, and the request it was created with unless req is nil.
+// Nothing is saved without a data directory.
+func saveTree(name string, req *TreeRequest, tree *merkletree.MerkleTree) error {
+	if dataDir == "" {
+		return nil
+	}
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid tree name %q", name)
+	}
+	if req != nil {
+		// The data is in the snapshot.
+		encoded, err := json.Marshal(TreeRequest{Name: req.Name, Hash: req.Hash, HashLength: req.HashLength, Tenant: req.Tenant})
+		if err != nil {
+			return err
+		}
+		if err := writeFile(filepath.Join(dataDir, name+requestExt), func(f *os.File) error {
+			_, err := f.Write(encoded)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return writeFile(filepath.Join(dataDir, name+snapshotExt), func(f *os.File) error {
+		_, err := tree.WriteTo(f)
+		return err
+	})
+}
+
+// writeFile writes a file through a temporary one renamed over it, so that a crash never leaves it half written.
+func writeFile(path string, write func(f *os.File) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,108 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/reactivejson/merkleTree/api"
+	merkletree "github.com/reactivejson/merkleTree/internal/merkle"
+	"github.com/reactivejson/merkleTree/internal/merkle/hash"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(api.ErrorHandler)
+	router.POST("/create", api.CreateTree)
+	router.PUT("/update", api.UpdateLeaf)
+	router.POST("/proof", api.GenerateProof)
+	return router
+}
+
+func serve(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+	return recorder
+}
+
+// root returns the root of the named tree, as carried by the proof of the data.
+func root(t *testing.T, router *gin.Engine, name, data string) []byte {
+	response := serve(router, http.MethodPost, "/proof", fmt.Sprintf(`{"name":%q,"data":%q}`, name, data))
+	assert.Equal(t, http.StatusOK, response.Code)
+	var proof merkletree.MerkleProof
+	assert.NoError(t, json.NewDecoder(response.Body).Decode(&proof))
+	return proof.Root
+}
+
+func TestPersistence(t *testing.T) {
+	defer api.ResetTrees()
+	api.ResetTrees()
+	dir := filepath.Join(t.TempDir(), "trees")
+	assert.NoError(t, api.LoadTrees(dir))
+	router := newRouter()
+
+	response := serve(router, http.MethodPost, "/create", `{"name":"t1","data":["Foo","Bar","Baz"],"hash":"sha256"}`)
+	assert.Equal(t, http.StatusOK, response.Code)
+	response = serve(router, http.MethodPut, "/update", `{"name":"t1","index":1,"data":"Qux"}`)
+	assert.Equal(t, http.StatusOK, response.Code)
+	want := root(t, router, "t1", "Qux")
+
+	// A failed update leaves the saved tree as it is.
+	response = serve(router, http.MethodPut, "/update", `{"name":"t1","index":3,"data":"Quux"}`)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), "index out of bounds")
+
+	saved, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, saved, 2)
+
+	// After a restart, the tree is restored with its update.
+	api.ResetTrees()
+	assert.NoError(t, api.LoadTrees(dir))
+	router = newRouter()
+	assert.Equal(t, want, root(t, router, "t1", "Qux"))
+	tree, err := merkletree.NewTree([][]byte{[]byte("Foo"), []byte("Qux"), []byte("Baz")}, hash.NewSHA256())
+	assert.NoError(t, err)
+	assert.Equal(t, tree.MerkleRoot(), want)
+}
+
+func TestPersistenceInvalidNames(t *testing.T) {
+	defer api.ResetTrees()
+	api.ResetTrees()
+	dir := t.TempDir()
+	router := newRouter()
+
+	// Without a data directory, any name is kept in memory.
+	response := serve(router, http.MethodPost, "/create", `{"name":".hidden","data":["Foo"]}`)
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	assert.NoError(t, api.LoadTrees(dir))
+	for i, name := range []string{"", "../t1", "a/b", ".hidden", ".."} {
+		response := serve(router, http.MethodPost, "/create", fmt.Sprintf(`{"name":%q,"data":["Foo"]}`, name))
+		assert.Equal(t, http.StatusBadRequest, response.Code, fmt.Sprintf("accepted name at test %d", i))
+	}
+	saved, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, saved)
+}
+
+func TestLoadTreesErrors(t *testing.T) {
+	defer api.ResetTrees()
+	api.ResetTrees()
+	dir := t.TempDir()
+
+	// A request without its snapshot can't be restored.
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "t1.json"), []byte(`{"name":"t1","hash":"sha256"}`), 0644))
+	assert.Error(t, api.LoadTrees(dir))
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "t1.tree"), []byte("not a snapshot"), 0644))
+	assert.EqualError(t, api.LoadTrees(dir), `failed to restore tree "t1": not a merkle tree snapshot`)
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+var (
+	tenantKeysMu sync.RWMutex
+	// tenantKeys maps each tenant to the BLAKE3 key its trees are built with. The keys never leave the server.
+	tenantKeys = make(map[string][32]byte)
+)
+
+// SetTenantKeys replaces the keys of the tenants.
+func SetTenantKeys(keys map[string][32]byte) {
+	tenantKeysMu.Lock()
+	defer tenantKeysMu.Unlock()
+	tenantKeys = keys
+}
+
+// tenantKey returns the key of the tenant.
+func tenantKey(tenant string) ([32]byte, error) {
+	tenantKeysMu.RLock()
+	defer tenantKeysMu.RUnlock()
+	key, ok := tenantKeys[tenant]
+	if !ok {
+		return key, fmt.Errorf("unknown tenant %q", tenant)
+	}
+	return key, nil
+}
+
+// ParseTenantKeys parses a comma separated list of tenant:key pairs, where each key is 32 bytes in hex,
+// e.g. "acme:000102...1f,globex:202122...3f".
+func ParseTenantKeys(s string) (map[string][32]byte, error) {
+	keys := make(map[string][32]byte)
+	if s == "" {
+		return keys, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		tenant, encoded, ok := strings.Cut(pair, ":")
+		if !ok || tenant == "" {
+			return nil, fmt.Errorf("invalid tenant key %q", pair)
+		}
+		var key [32]byte
+		decoded, err := hex.DecodeString(encoded)
+		if err != nil || len(decoded) != len(key) {
+			return nil, fmt.Errorf("the key of tenant %q must be 32 bytes in hex", tenant)
+		}
+		copy(key[:], decoded)
+		keys[tenant] = key
+	}
+	return keys, nil
+}
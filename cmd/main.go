@@ -1,8 +1,19 @@
 package main
 
 import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/reactivejson/merkleTree/api"
+	merkletree "github.com/reactivejson/merkleTree/internal/merkle"
+	"github.com/reactivejson/merkleTree/internal/merkle/hash"
+	"io"
+	"log"
+	"os"
+	"strings"
 )
 
 /**
@@ -11,11 +22,125 @@ import (
  */
 // The core entry point into the app. will setup the config, and run the App
 func main() {
+	// proof and verify run the command line instead of the server.
+	if len(os.Args) > 1 && isCommand(os.Args[1]) {
+		exitCLI(os.Args[1:])
+	}
+
+	// The keys of the tenants trees can be bound to, as tenant:hexkey pairs separated by commas.
+	keys, err := api.ParseTenantKeys(os.Getenv("MERKLE_TENANT_KEYS"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	api.SetTenantKeys(keys)
+	// The trees are saved to the data directory, and restored from it at startup.
+	if dir := os.Getenv("MERKLE_DATA_DIR"); dir != "" {
+		if err := api.LoadTrees(dir); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	router := gin.Default()
 	router.Use(api.ErrorHandler)
 	router.POST("/create", api.CreateTree)
 	router.PUT("/update", api.UpdateLeaf)
+	router.POST("/proof", api.GenerateProof)
 	router.POST("/verify", api.VerifyProof)
 	router.POST("/visual/proof", api.VisualizeProof)
 	router.Run(":8080")
 }
+
+// isCommand tells whether the first argument is a command of the command line.
+func isCommand(arg string) bool {
+	return arg == "proof" || arg == "verify"
+}
+
+// runCLI runs a command of the command line instead of the server:
+//   - proof [-hash name] [-index n] data...: prints the JSON proof of the leaf at the index of a tree of the data;
+//   - verify -data value -root hex: reads a JSON proof from stdin and prints whether it proves the data against the
+//     root, which is given rather than taken from the proof so that it can be trusted.
+func runCLI(args []string, stdin io.Reader, stdout io.Writer) error {
+	switch args[0] {
+	case "proof":
+		return proofCommand(args[1:], stdout)
+	case "verify":
+		return verifyCommand(args[1:], stdin, stdout)
+	default:
+		return fmt.Errorf("unknown command %q, expected proof or verify", args[0])
+	}
+}
+
+func proofCommand(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("proof", flag.ContinueOnError)
+	name := flags.String("hash", "blake3", "the hash type of the tree")
+	index := flags.Uint64("index", 0, "the index of the leaf to prove")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("no data to build the tree from")
+	}
+
+	hashType, err := hash.ByName(*name)
+	if err != nil {
+		return err
+	}
+	data := make([][]byte, flags.NArg())
+	for i, arg := range flags.Args() {
+		data[i] = []byte(arg)
+	}
+	tree, err := merkletree.NewTree(data, hashType)
+	if err != nil {
+		return err
+	}
+	proof, err := tree.GenerateMProofAt(*index)
+	if err != nil {
+		return err
+	}
+	encoded, err := json.MarshalIndent(proof, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, string(encoded))
+	return err
+}
+
+func verifyCommand(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	data := flags.String("data", "", "the data the proof proves")
+	rootHex := flags.String("root", "", "the trusted root of the tree, in hex")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *rootHex == "" {
+		return errors.New("the root of the tree is required")
+	}
+	root, err := hex.DecodeString(strings.TrimPrefix(*rootHex, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid root %q", *rootHex)
+	}
+
+	var proof merkletree.MerkleProof
+	if err := json.NewDecoder(stdin).Decode(&proof); err != nil {
+		return err
+	}
+	verified, err := proof.Verify([]byte(*data), root)
+	if err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(map[string]bool{"verified": verified})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, string(encoded))
+	return err
+}
+
+// exitCLI runs the command line and exits with a non-zero status on errors.
+func exitCLI(args []string) {
+	if err := runCLI(args, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
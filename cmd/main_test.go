@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	merkletree "github.com/reactivejson/merkleTree/internal/merkle"
+	"github.com/reactivejson/merkleTree/internal/merkle/hash"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsCommand(t *testing.T) {
+	assert.True(t, isCommand("proof"))
+	assert.True(t, isCommand("verify"))
+	// Anything else starts the server.
+	assert.False(t, isCommand("-port=8080"))
+	assert.False(t, isCommand("serve"))
+}
+
+func TestProofCommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{ // 0
+			args: []string{"proof", "-hash", "sha256", "-index", "2", "Foo", "Bar", "Baz"},
+		},
+		{ // 1
+			args: []string{"proof", "Foo"},
+		},
+		{ // 2
+			args: []string{"proof", "-nope", "Foo"},
+			err:  "flag provided but not defined: -nope",
+		},
+		{ // 3
+			args: []string{"proof", "-hash", "sha256"},
+			err:  "no data to build the tree from",
+		},
+		{ // 4
+			args: []string{"proof", "-hash", "md5", "Foo"},
+			err:  `unknown hash type "md5"`,
+		},
+		{ // 5
+			args: []string{"proof", "-index", "3", "Foo", "Bar", "Baz"},
+			err:  "index out of bounds",
+		},
+		{ // 6
+			args: []string{"prove", "Foo"},
+			err:  `unknown command "prove", expected proof or verify`,
+		},
+	}
+
+	for i, test := range tests {
+		var stdout bytes.Buffer
+		err := runCLI(test.args, strings.NewReader(""), &stdout)
+		if test.err != "" {
+			assert.EqualError(t, err, test.err, fmt.Sprintf("unexpected error at test %d", i))
+			assert.Empty(t, stdout.String(), fmt.Sprintf("unexpected output at test %d", i))
+			continue
+		}
+		assert.NoError(t, err, fmt.Sprintf("unexpected error at test %d", i))
+		var proof merkletree.MerkleProof
+		assert.NoError(t, json.Unmarshal(stdout.Bytes(), &proof), fmt.Sprintf("failed to decode proof at test %d", i))
+		data := test.args[len(test.args)-1]
+		verified, err := proof.Verify([]byte(data), proof.Root)
+		assert.NoError(t, err)
+		assert.True(t, verified, fmt.Sprintf("failed to verify proof at test %d", i))
+	}
+}
+
+func TestVerifyCommand(t *testing.T) {
+	data := [][]byte{[]byte("Foo"), []byte("Bar"), []byte("Baz")}
+	tree, err := merkletree.NewTree(data, hash.NewSHA256())
+	assert.NoError(t, err)
+	proof, err := tree.GenerateMProofAt(2)
+	assert.NoError(t, err)
+	encoded, err := json.Marshal(proof)
+	assert.NoError(t, err)
+	root := "0x" + hex.EncodeToString(tree.MerkleRoot())
+
+	tampered := *proof
+	tampered.Hashes = [][]byte{proof.Hashes[1], proof.Hashes[0]}
+	tamperedEncoded, err := json.Marshal(&tampered)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		args   []string
+		stdin  string
+		output string
+		err    string
+	}{
+		{ // 0
+			args:   []string{"verify", "-data", "Baz", "-root", root},
+			stdin:  string(encoded),
+			output: `{"verified":true}` + "\n",
+		},
+		{ // 1: the root may be given without its 0x prefix.
+			args:   []string{"verify", "-data", "Baz", "-root", root[2:]},
+			stdin:  string(encoded),
+			output: `{"verified":true}` + "\n",
+		},
+		{ // 2
+			args:   []string{"verify", "-data", "Foo", "-root", root},
+			stdin:  string(encoded),
+			output: `{"verified":false}` + "\n",
+		},
+		{ // 3
+			args:   []string{"verify", "-data", "Baz", "-root", root},
+			stdin:  string(tamperedEncoded),
+			output: `{"verified":false}` + "\n",
+		},
+		{ // 4
+			args:  []string{"verify", "-data", "Baz", "-root", root},
+			stdin: strings.Replace(string(encoded), `"leafCount":3`, `"leafCount":2`, 1),
+			err:   "index out of bounds",
+		},
+		{ // 5
+			args:  []string{"verify", "-data", "Baz"},
+			stdin: string(encoded),
+			err:   "the root of the tree is required",
+		},
+		{ // 6
+			args:  []string{"verify", "-data", "Baz", "-root", "0xzz"},
+			stdin: string(encoded),
+			err:   `invalid root "0xzz"`,
+		},
+		{ // 7
+			args:  []string{"verify", "-data", "Baz", "-root", root, "-nope"},
+			stdin: string(encoded),
+			err:   "flag provided but not defined: -nope",
+		},
+		{ // 8
+			args:  []string{"verify", "-data", "Baz", "-root", root},
+			stdin: "not a proof",
+			err:   "invalid character 'o' in literal null (expecting 'u')",
+		},
+	}
+
+	for i, test := range tests {
+		var stdout bytes.Buffer
+		err := runCLI(test.args, strings.NewReader(test.stdin), &stdout)
+		if test.err != "" {
+			assert.EqualError(t, err, test.err, fmt.Sprintf("unexpected error at test %d", i))
+		} else {
+			assert.NoError(t, err, fmt.Sprintf("unexpected error at test %d", i))
+		}
+		assert.Equal(t, test.output, stdout.String(), fmt.Sprintf("unexpected output at test %d", i))
+	}
+}
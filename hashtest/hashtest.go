@@ -0,0 +1,198 @@
+// Package hashtest checks that implementations of hash.HashType behave like the hash types of the package hash, so
+// that they can be used to build and verify merkle trees. Hash types only need the methods of hash.HashType, and
+// hash.Streamer if they stream, to be checked from outside this module.
+package hashtest
+
+import (
+	"bytes"
+	"fmt"
+	merkletree "github.com/reactivejson/merkleTree/internal/merkle"
+	hash2 "github.com/reactivejson/merkleTree/internal/merkle/hash"
+	"sync"
+	"testing"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// inputs are the inputs hashed by the checks, including empty ones and one longer than the blocks of common hashes.
+var inputs = [][]byte{
+	nil,
+	{},
+	{0},
+	[]byte("abc"),
+	[]byte("Merle-tree"),
+	bytes.Repeat([]byte{0xa5}, 1031),
+}
+
+// RunConformance runs the conformance checks of hash types on h, each one as a subtest of t:
+//   - Determinism: hashing the same input twice gives the same hash, and neither the input nor the hashes
+//     returned before are modified.
+//   - Concat: hashing several byte arrays gives the hash of their concatenation.
+//   - HashLength: every hash is HashLength() bytes long, and a Streamer's hashers agree with Hash.
+//   - Concurrency: h can be used from several goroutines at once; run the tests with -race to catch data races.
+//   - Tree: trees built with h give proofs that VerifyMProof accepts, and rejects for other data.
+//
+// Hash types that don't hash the concatenation of their inputs, such as Poseidon, don't conform.
+func RunConformance(t *testing.T, h hash2.HashType) {
+	t.Run("Determinism", func(t *testing.T) { checkDeterminism(t, h) })
+	t.Run("Concat", func(t *testing.T) { checkConcat(t, h) })
+	t.Run("HashLength", func(t *testing.T) { checkHashLength(t, h) })
+	t.Run("Concurrency", func(t *testing.T) { checkConcurrency(t, h) })
+	t.Run("Tree", func(t *testing.T) { checkTree(t, h) })
+}
+
+func checkDeterminism(t *testing.T, h hash2.HashType) {
+	hashes := make([][]byte, len(inputs))
+	for i, input := range inputs {
+		original := append([]byte(nil), input...)
+		hashes[i] = h.Hash(input)
+		if !bytes.Equal(original, input) {
+			t.Errorf("input modified at test %d", i)
+		}
+	}
+
+	seen := make(map[string]int, len(inputs))
+	for i, input := range inputs {
+		first := append([]byte(nil), hashes[i]...)
+		if !bytes.Equal(first, h.Hash(input)) {
+			t.Errorf("hash changed at test %d", i)
+		}
+		// A hash returned before must not be overwritten by the next ones.
+		h.Hash([]byte("overwrite"))
+		if !bytes.Equal(first, hashes[i]) {
+			t.Errorf("returned hash modified at test %d", i)
+		}
+
+		if len(input) > 0 {
+			if j, dup := seen[string(hashes[i])]; dup {
+				t.Errorf("same hash at tests %d and %d", j, i)
+			}
+			seen[string(hashes[i])] = i
+		}
+	}
+}
+
+func checkConcat(t *testing.T, h hash2.HashType) {
+	data := []byte("Merle-tree Blake3 Consensys")
+	want := h.Hash(data)
+	for i := 0; i <= len(data); i++ {
+		if !bytes.Equal(want, h.Hash(data[:i], data[i:])) {
+			t.Errorf("failed to split at %d", i)
+		}
+		for j := i; j <= len(data); j += 5 {
+			if !bytes.Equal(want, h.Hash(data[:i], data[i:j], nil, data[j:])) {
+				t.Errorf("failed to split at %d and %d", i, j)
+			}
+		}
+	}
+	if !bytes.Equal(h.Hash(), h.Hash(nil)) {
+		t.Error("hash of no input differs from hash of empty input")
+	}
+	if !bytes.Equal(h.Hash(nil), h.Hash([]byte{}, nil)) {
+		t.Error("hash of empty inputs differs")
+	}
+}
+
+func checkHashLength(t *testing.T, h hash2.HashType) {
+	length := h.HashLength()
+	if length <= 0 {
+		t.Errorf("hash length %d is not positive", length)
+		return
+	}
+	for i, input := range inputs {
+		if got := len(h.Hash(input)); got != length {
+			t.Errorf("unexpected hash length %d at test %d", got, i)
+		}
+	}
+
+	streamer, ok := h.(hash2.Streamer)
+	if !ok {
+		return
+	}
+	hasher := streamer.NewHasher()
+	if hasher.Size() != length {
+		t.Errorf("hasher size %d differs from the hash length", hasher.Size())
+	}
+	for i, input := range inputs {
+		hasher.Reset()
+		hasher.Write(input[:len(input)/2])
+		hasher.Write(input[len(input)/2:])
+		prefix := []byte("prefix")
+		if !bytes.Equal(append(prefix, h.Hash(input)...), hasher.Sum(prefix)) {
+			t.Errorf("hasher differs from Hash at test %d", i)
+		}
+	}
+}
+
+func checkConcurrency(t *testing.T, h hash2.HashType) {
+	want := make([][]byte, len(inputs))
+	for i, input := range inputs {
+		want[i] = h.Hash(input)
+	}
+
+	const goroutines = 8
+	results := make([][][]byte, goroutines)
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for round := 0; round < 16; round++ {
+				for _, input := range inputs {
+					results[g] = append(results[g], h.Hash(input))
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for g, hashes := range results {
+		for j, hash := range hashes {
+			if !bytes.Equal(want[j%len(inputs)], hash) {
+				t.Errorf("unexpected hash in goroutine %d at test %d", g, j)
+			}
+		}
+	}
+}
+
+func checkTree(t *testing.T, h hash2.HashType) {
+	tests := [][]merkletree.Option{
+		nil,
+		{merkletree.WithRFC6962Prefixes()},
+		{merkletree.WithShape(merkletree.ShapeDuplicateLast)},
+		{merkletree.WithShape(merkletree.ShapePromoteOdd), merkletree.WithWorkers(4)},
+	}
+
+	data := make([][]byte, 7)
+	for i := range data {
+		data[i] = []byte(fmt.Sprintf("leaf-%d", i))
+	}
+	for i, opts := range tests {
+		tree, err := merkletree.NewTree(data, h, opts...)
+		if err != nil {
+			t.Errorf("failed to build tree at test %d: %v", i, err)
+			continue
+		}
+		root := tree.MerkleRoot()
+		if len(root) != h.HashLength() {
+			t.Errorf("unexpected root length %d at test %d", len(root), i)
+		}
+
+		for j, d := range data {
+			proof, err := tree.GenerateMProof(d)
+			if err != nil {
+				t.Errorf("failed to generate proof at test %d input %d: %v", i, j, err)
+				continue
+			}
+			if verified, err := merkletree.VerifyMProof(d, proof, root, h, opts...); err != nil || !verified {
+				t.Errorf("failed to verify proof at test %d input %d: %v", i, j, err)
+			}
+			if verified, err := merkletree.VerifyMProof([]byte("other"), proof, root, h, opts...); err != nil || verified {
+				t.Errorf("verified wrong input at test %d input %d: %v", i, j, err)
+			}
+		}
+	}
+}
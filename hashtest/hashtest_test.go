@@ -0,0 +1,33 @@
+package hashtest_test
+
+import (
+	"github.com/reactivejson/merkleTree/hashtest"
+	hash2 "github.com/reactivejson/merkleTree/internal/merkle/hash"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	blake3x16, _ := hash2.NewBlake3WithLength(16)
+	blake3x64, _ := hash2.NewBlake3WithLength(64)
+	hashes := map[string]hash2.HashType{
+		"blake3-keyed":      hash2.NewBlake3Keyed([32]byte{1}),
+		"blake3-derive-key": hash2.NewBlake3DeriveKey("hashtest"),
+		"blake3-16":         blake3x16,
+		"blake3-64":         blake3x64,
+	}
+	for _, name := range hash2.Names() {
+		// Poseidon hashes field elements rather than their concatenation.
+		if name == "poseidon" {
+			continue
+		}
+		h, err := hash2.ByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes[name] = h
+	}
+
+	for name, h := range hashes {
+		t.Run(name, func(t *testing.T) { hashtest.RunConformance(t, h) })
+	}
+}
@@ -0,0 +1,203 @@
+package merkletree
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// abiWord is the size of a slot of the Solidity ABI encoding.
+const abiWord = 32
+
+// abiEncode encodes the values as Solidity's abi.encode(values...) does with the given types, which is how
+// OpenZeppelin's StandardMerkleTree encodes its leaves. The supported types are address, bool, uint<N>, int<N>,
+// bytes<N>, bytes and string.
+//
+// Integers are given as decimal or 0x-prefixed hexadecimal strings, json.Number, Go integers or *big.Int; addresses
+// and byte arrays as 0x-prefixed hexadecimal strings or []byte.
+func abiEncode(types []string, values []interface{}) ([]byte, error) {
+	if len(types) != len(values) {
+		return nil, fmt.Errorf("expected %d values for the leaf encoding, got %d", len(types), len(values))
+	}
+
+	head := make([]byte, 0, abiWord*len(types))
+	var tail []byte
+	for i, typ := range types {
+		switch typ {
+		case "bytes", "string":
+			data, err := abiDynamic(typ, values[i])
+			if err != nil {
+				return nil, err
+			}
+			offset := new(big.Int).SetInt64(int64(abiWord*len(types) + len(tail)))
+			head = append(head, abiPad(offset.Bytes())...)
+			length := new(big.Int).SetInt64(int64(len(data)))
+			tail = append(tail, abiPad(length.Bytes())...)
+			tail = append(tail, data...)
+			if rem := len(data) % abiWord; rem != 0 {
+				tail = append(tail, make([]byte, abiWord-rem)...)
+			}
+		default:
+			word, err := abiStatic(typ, values[i])
+			if err != nil {
+				return nil, err
+			}
+			head = append(head, word...)
+		}
+	}
+	return append(head, tail...), nil
+}
+
+// abiStatic encodes a value of a static type in a single word.
+func abiStatic(typ string, value interface{}) ([]byte, error) {
+	switch {
+	case typ == "address":
+		b, err := abiBytes(value)
+		if err != nil || len(b) != 20 {
+			return nil, fmt.Errorf("invalid address value %v", value)
+		}
+		return abiPad(b), nil
+
+	case typ == "bool":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("invalid bool value %v", value)
+		}
+		if b {
+			return abiPad([]byte{1}), nil
+		}
+		return abiPad(nil), nil
+
+	case strings.HasPrefix(typ, "uint"), strings.HasPrefix(typ, "int"):
+		signed := strings.HasPrefix(typ, "int")
+		bits, err := abiSize(strings.TrimPrefix(strings.TrimPrefix(typ, "u"), "int"), 256, 8)
+		if err != nil || bits%8 != 0 {
+			return nil, fmt.Errorf("unsupported type %s", typ)
+		}
+		n, err := abiInteger(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %v", typ, value)
+		}
+		lo, hi := new(big.Int), new(big.Int).Lsh(big.NewInt(1), uint(bits))
+		if signed {
+			hi.Rsh(hi, 1)
+			lo.Neg(hi)
+		}
+		if n.Cmp(lo) < 0 || n.Cmp(hi) >= 0 {
+			return nil, fmt.Errorf("%s value %v out of range", typ, value)
+		}
+		if n.Sign() < 0 {
+			// Two's complement on 256 bits.
+			n = new(big.Int).Add(n, new(big.Int).Lsh(big.NewInt(1), 8*abiWord))
+		}
+		return abiPad(n.Bytes()), nil
+
+	case strings.HasPrefix(typ, "bytes"):
+		size, err := abiSize(strings.TrimPrefix(typ, "bytes"), 0, 1)
+		if err != nil || size > abiWord {
+			return nil, fmt.Errorf("unsupported type %s", typ)
+		}
+		b, err := abiBytes(value)
+		if err != nil || len(b) != size {
+			return nil, fmt.Errorf("invalid %s value %v", typ, value)
+		}
+		// Fixed-size byte arrays are padded on the right.
+		return append(b, make([]byte, abiWord-size)...), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported type %s", typ)
+	}
+}
+
+// abiDynamic returns the content of a value of a dynamic type, before its length and padding are added.
+func abiDynamic(typ string, value interface{}) ([]byte, error) {
+	if typ == "string" {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid string value %v", value)
+		}
+		return []byte(s), nil
+	}
+	b, err := abiBytes(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bytes value %v", value)
+	}
+	return b, nil
+}
+
+// abiSize parses the size suffix of a type such as uint64 or bytes4, which must be from step to 32*step.
+// An empty suffix stands for the default size, when there is one.
+func abiSize(suffix string, defaultSize, step int) (int, error) {
+	if suffix == "" && defaultSize != 0 {
+		return defaultSize, nil
+	}
+	size, err := strconv.Atoi(suffix)
+	if err != nil || size < step || size > 32*step || suffix != strconv.Itoa(size) {
+		return 0, fmt.Errorf("invalid size %q", suffix)
+	}
+	return size, nil
+}
+
+// abiPad left-pads b with zeros to a word.
+func abiPad(b []byte) []byte {
+	word := make([]byte, abiWord)
+	copy(word[abiWord-len(b):], b)
+	return word
+}
+
+// abiBytes returns the bytes of a 0x-prefixed hexadecimal string or of a []byte.
+func abiBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return append([]byte(nil), v...), nil
+	case string:
+		if !strings.HasPrefix(v, "0x") && !strings.HasPrefix(v, "0X") {
+			return nil, fmt.Errorf("missing 0x prefix in %q", v)
+		}
+		return hex.DecodeString(v[2:])
+	default:
+		return nil, fmt.Errorf("invalid bytes value %v", value)
+	}
+}
+
+// abiInteger returns the integer held by a value.
+func abiInteger(value interface{}) (*big.Int, error) {
+	var s string
+	switch v := value.(type) {
+	case *big.Int:
+		return new(big.Int).Set(v), nil
+	case int:
+		return big.NewInt(int64(v)), nil
+	case int64:
+		return big.NewInt(v), nil
+	case uint64:
+		return new(big.Int).SetUint64(v), nil
+	case json.Number:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return nil, fmt.Errorf("invalid integer value %v", value)
+	}
+
+	digits, base := strings.TrimPrefix(s, "-"), 10
+	if strings.HasPrefix(digits, "0x") {
+		digits, base = digits[2:], 16
+	}
+	n, ok := new(big.Int).SetString(digits, base)
+	if !ok || strings.HasPrefix(digits, "+") || strings.HasPrefix(digits, "-") {
+		return nil, fmt.Errorf("invalid integer value %q", s)
+	}
+	if strings.HasPrefix(s, "-") {
+		n.Neg(n)
+	}
+	return n, nil
+}
@@ -0,0 +1,374 @@
+package merkletree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/reactivejson/merkleTree/internal/merkle/hash"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// bitcoinMaxTransactions is the largest number of transactions a block can hold, the maximum weight of a block
+// divided by the minimum weight of a transaction, above which a partial merkle tree is rejected as Bitcoin Core does.
+const bitcoinMaxTransactions = 4000000 / 240
+
+// bitcoinHasher hashes the nodes of the merkle tree of a Bitcoin block: double SHA-256 of the two children, the
+// last node of an odd level being paired with itself.
+var bitcoinHasher = newTreeHasher(hash.NewDoubleSHA256(), DomainSeparation{}, ShapeDuplicateLast, false)
+
+// ParseTxid parses a transaction or block hash as it is displayed by Bitcoin nodes and block explorers, and returns
+// it in the internal byte order used to hash the tree, which is the reverse of the displayed one.
+func ParseTxid(s string) ([]byte, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 32 {
+		return nil, fmt.Errorf("invalid txid %q", s)
+	}
+	return reverseBytes(b), nil
+}
+
+// TxidString returns a transaction or block hash in internal byte order as it is displayed by Bitcoin nodes.
+func TxidString(txid []byte) string {
+	return hex.EncodeToString(reverseBytes(txid))
+}
+
+// reverseBytes returns a reversed copy of b.
+func reverseBytes(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
+
+// BitcoinMerkleRoot computes the merkle root of a Bitcoin block from the txids of its transactions, in the order of
+// the block and in internal byte order (see ParseTxid). The txids are the leaves of the tree as they are, and the
+// root is returned in internal byte order too.
+//
+// The duplication of the last node of odd levels lets a list of transactions ending with a repeated subtree have
+// the same root as the list without it (CVE-2012-2459), so such lists are an error, as they are for Bitcoin Core.
+func BitcoinMerkleRoot(txids [][]byte) ([]byte, error) {
+	if len(txids) == 0 {
+		return nil, errors.New("the block should contain at least 1 transaction")
+	}
+	level := make([][]byte, len(txids))
+	for i, txid := range txids {
+		if len(txid) != 32 {
+			return nil, fmt.Errorf("invalid txid length %d at index %d", len(txid), i)
+		}
+		level[i] = txid
+	}
+
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, bitcoinHasher.node(level[i], level[i]))
+				break
+			}
+			if bytes.Equal(level[i], level[i+1]) {
+				return nil, errors.New("the transactions contain a duplicated subtree (CVE-2012-2459)")
+			}
+			next = append(next, bitcoinHasher.node(level[i], level[i+1]))
+		}
+		level = next
+	}
+	return level[0], nil
+}
+
+// PartialMerkleTree is a proof that some transactions are in a Bitcoin block, in the format of the merkleblock
+// message of BIP 37: the tree is traversed depth-first from the root, and every node visited has a flag telling
+// whether it is an ancestor of a matched transaction. The descendants of a flagged node are visited, and the hash of
+// every other node visited, as well as of the matched transactions, is included.
+type PartialMerkleTree struct {
+	Transactions uint32   // The number of transactions in the block
+	Hashes       [][]byte // The hashes of the nodes that are not computed, in internal byte order, in depth-first order
+	Flags        []bool   // The flag of every node visited, in depth-first order
+}
+
+// bitcoinWidth returns the number of nodes at the given height of the tree of a block with n transactions,
+// the leaves being at height 0.
+func bitcoinWidth(n uint32, height uint) uint32 {
+	return uint32((uint64(n) + 1<<height - 1) >> height)
+}
+
+// bitcoinHeight returns the height of the root of the tree of a block with n transactions.
+func bitcoinHeight(n uint32) uint {
+	var height uint
+	for bitcoinWidth(n, height) > 1 {
+		height++
+	}
+	return height
+}
+
+// NewPartialMerkleTree builds the partial merkle tree proving the transactions of a block whose match flag is set.
+// txids are the txids of all the transactions of the block, in the order of the block and in internal byte order.
+func NewPartialMerkleTree(txids [][]byte, matches []bool) (*PartialMerkleTree, error) {
+	if len(txids) == 0 {
+		return nil, errors.New("the block should contain at least 1 transaction")
+	}
+	if len(matches) != len(txids) {
+		return nil, errors.New("the matches should have the length of the transactions")
+	}
+	for i, txid := range txids {
+		if len(txid) != 32 {
+			return nil, fmt.Errorf("invalid txid length %d at index %d", len(txid), i)
+		}
+	}
+
+	p := &PartialMerkleTree{Transactions: uint32(len(txids))}
+	p.build(bitcoinHeight(p.Transactions), 0, txids, matches)
+	return p, nil
+}
+
+// build visits the node at the given height and position, and its descendants if they are ancestors of a match.
+func (p *PartialMerkleTree) build(height uint, pos uint32, txids [][]byte, matches []bool) {
+	parentOfMatch := false
+	for i := uint64(pos) << height; i < uint64(pos+1)<<height && i < uint64(p.Transactions); i++ {
+		parentOfMatch = parentOfMatch || matches[i]
+	}
+	p.Flags = append(p.Flags, parentOfMatch)
+
+	if height == 0 || !parentOfMatch {
+		p.Hashes = append(p.Hashes, p.hashAt(height, pos, txids))
+		return
+	}
+	p.build(height-1, 2*pos, txids, matches)
+	if 2*pos+1 < bitcoinWidth(p.Transactions, height-1) {
+		p.build(height-1, 2*pos+1, txids, matches)
+	}
+}
+
+// hashAt computes the hash of the node at the given height and position from all the txids.
+func (p *PartialMerkleTree) hashAt(height uint, pos uint32, txids [][]byte) []byte {
+	if height == 0 {
+		return txids[pos]
+	}
+	left := p.hashAt(height-1, 2*pos, txids)
+	right := left
+	if 2*pos+1 < bitcoinWidth(p.Transactions, height-1) {
+		right = p.hashAt(height-1, 2*pos+1, txids)
+	}
+	return bitcoinHasher.node(left, right)
+}
+
+// ExtractMatches recomputes the merkle root from the partial merkle tree, and returns it with the matched txids and
+// their positions in the block. The root must be compared with the one of the block header for the matches to be
+// proven. Malformed trees are an error, including trees where a node has two identical children (CVE-2012-2459).
+func (p *PartialMerkleTree) ExtractMatches() (root []byte, matches [][]byte, indices []uint32, err error) {
+	switch {
+	case p.Transactions == 0:
+		return nil, nil, nil, errors.New("the partial merkle tree has no transactions")
+	case p.Transactions > bitcoinMaxTransactions:
+		return nil, nil, nil, errors.New("the partial merkle tree has too many transactions")
+	case len(p.Hashes) > int(p.Transactions):
+		return nil, nil, nil, errors.New("the partial merkle tree has more hashes than transactions")
+	case len(p.Flags) < len(p.Hashes):
+		return nil, nil, nil, errors.New("the partial merkle tree has fewer flags than hashes")
+	}
+	for _, h := range p.Hashes {
+		if len(h) != 32 {
+			return nil, nil, nil, ErrHashLength
+		}
+	}
+
+	e := &extraction{p: p}
+	root = e.extract(bitcoinHeight(p.Transactions), 0)
+	switch {
+	case e.err != nil:
+		return nil, nil, nil, e.err
+	// The flags are serialized in whole bytes, so only the padding of the last byte can be left over.
+	case (e.flags+7)/8 != (len(p.Flags)+7)/8:
+		return nil, nil, nil, errors.New("the partial merkle tree has unused flags")
+	case e.hashes != len(p.Hashes):
+		return nil, nil, nil, errors.New("the partial merkle tree has unused hashes")
+	}
+	return root, e.matches, e.indices, nil
+}
+
+// extraction is the state of the traversal of a partial merkle tree by ExtractMatches.
+type extraction struct {
+	p       *PartialMerkleTree
+	flags   int // The number of flags used
+	hashes  int // The number of hashes used
+	matches [][]byte
+	indices []uint32
+	err     error
+}
+
+// extract returns the hash of the node at the given height and position, consuming flags and hashes.
+func (e *extraction) extract(height uint, pos uint32) []byte {
+	if e.err != nil {
+		return nil
+	}
+	if e.flags >= len(e.p.Flags) {
+		e.err = errors.New("the partial merkle tree has too few flags")
+		return nil
+	}
+	parentOfMatch := e.p.Flags[e.flags]
+	e.flags++
+
+	if height == 0 || !parentOfMatch {
+		if e.hashes >= len(e.p.Hashes) {
+			e.err = errors.New("the partial merkle tree has too few hashes")
+			return nil
+		}
+		h := e.p.Hashes[e.hashes]
+		e.hashes++
+		if height == 0 && parentOfMatch {
+			e.matches = append(e.matches, h)
+			e.indices = append(e.indices, pos)
+		}
+		return h
+	}
+
+	left := e.extract(height-1, 2*pos)
+	right := left
+	if 2*pos+1 < bitcoinWidth(e.p.Transactions, height-1) {
+		right = e.extract(height-1, 2*pos+1)
+		if e.err == nil && bytes.Equal(left, right) {
+			e.err = errors.New("the partial merkle tree has a duplicated subtree (CVE-2012-2459)")
+		}
+	}
+	if e.err != nil {
+		return nil
+	}
+	return bitcoinHasher.node(left, right)
+}
+
+// VerifyBitcoinProof verifies that a partial merkle tree proves the inclusion of a transaction in the block with the
+// given merkle root. The txid and the root are in internal byte order.
+//
+// This returns true if the proof is verified, otherwise false. An error is returned for a malformed proof.
+func VerifyBitcoinProof(txid []byte, proof *PartialMerkleTree, root []byte) (bool, error) {
+	computed, matches, _, err := proof.ExtractMatches()
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(computed, root) {
+		return false, nil
+	}
+	for _, match := range matches {
+		if bytes.Equal(match, txid) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// MarshalBinary encodes the partial merkle tree as in the merkleblock message: the number of transactions as a
+// little-endian uint32, the hashes and the flags, each preceded by their count as a Bitcoin variable length integer.
+// The flags are packed in bytes, least significant bit first.
+func (p *PartialMerkleTree) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 4, 4+9+32*len(p.Hashes)+9+(len(p.Flags)+7)/8)
+	binary.LittleEndian.PutUint32(b, p.Transactions)
+	b = appendCompactSize(b, uint64(len(p.Hashes)))
+	for _, h := range p.Hashes {
+		if len(h) != 32 {
+			return nil, ErrHashLength
+		}
+		b = append(b, h...)
+	}
+
+	flags := make([]byte, (len(p.Flags)+7)/8)
+	for i, flag := range p.Flags {
+		if flag {
+			flags[i/8] |= 1 << (i % 8)
+		}
+	}
+	b = appendCompactSize(b, uint64(len(flags)))
+	return append(b, flags...), nil
+}
+
+// UnmarshalBinary decodes a partial merkle tree encoded by MarshalBinary or found in a merkleblock message after the
+// block header. Every bit of the flag bytes becomes a flag, including the padding of the last byte.
+func (p *PartialMerkleTree) UnmarshalBinary(data []byte) error {
+	if len(data) < 4 {
+		return errors.New("the partial merkle tree is truncated")
+	}
+	transactions := binary.LittleEndian.Uint32(data)
+	data = data[4:]
+
+	count, data, err := readCompactSize(data)
+	if err != nil {
+		return err
+	}
+	if count > uint64(len(data))/32 {
+		return errors.New("the partial merkle tree is truncated")
+	}
+	hashes := make([][]byte, count)
+	for i := range hashes {
+		hashes[i] = append([]byte(nil), data[:32]...)
+		data = data[32:]
+	}
+
+	count, data, err = readCompactSize(data)
+	if err != nil {
+		return err
+	}
+	if count != uint64(len(data)) {
+		return errors.New("the partial merkle tree has a wrong length")
+	}
+	flags := make([]bool, 8*len(data))
+	for i := range flags {
+		flags[i] = data[i/8]&(1<<(i%8)) != 0
+	}
+
+	*p = PartialMerkleTree{Transactions: transactions, Hashes: hashes, Flags: flags}
+	return nil
+}
+
+// appendCompactSize appends a Bitcoin variable length integer to b.
+func appendCompactSize(b []byte, n uint64) []byte {
+	var size int
+	switch {
+	case n < 0xfd:
+		return append(b, byte(n))
+	case n <= 0xffff:
+		b, size = append(b, 0xfd), 2
+	case n <= 0xffffffff:
+		b, size = append(b, 0xfe), 4
+	default:
+		b, size = append(b, 0xff), 8
+	}
+	for i := 0; i < size; i++ {
+		b = append(b, byte(n>>(8*i)))
+	}
+	return b
+}
+
+// readCompactSize reads a Bitcoin variable length integer, which must be minimally encoded, and returns it with the
+// rest of the data.
+func readCompactSize(data []byte) (uint64, []byte, error) {
+	if len(data) == 0 {
+		return 0, nil, errors.New("the partial merkle tree is truncated")
+	}
+	var size int
+	var min uint64
+	switch data[0] {
+	case 0xfd:
+		size, min = 2, 0xfd
+	case 0xfe:
+		size, min = 4, 0x10000
+	case 0xff:
+		size, min = 8, 0x100000000
+	default:
+		return uint64(data[0]), data[1:], nil
+	}
+	if len(data) < 1+size {
+		return 0, nil, errors.New("the partial merkle tree is truncated")
+	}
+	var n uint64
+	for i := size; i > 0; i-- {
+		n = n<<8 | uint64(data[i])
+	}
+	if n < min {
+		return 0, nil, errors.New("non-canonical variable length integer")
+	}
+	return n, data[1+size:], nil
+}
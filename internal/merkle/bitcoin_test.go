@@ -0,0 +1,198 @@
+package merkletree_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	merkletree "github.com/reactivejson/merkleTree/internal/merkle"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// bitcoinBlock holds the merkle root and txids of a mainnet block, as displayed by Bitcoin nodes.
+type bitcoinBlock struct {
+	Height            uint64   `json:"height"`
+	MerkleRoot        string   `json:"merkleRoot"`
+	Txids             []string `json:"txids"`
+	PartialMerkleTree *struct {
+		Matches []int  `json:"matches"`
+		Encoded string `json:"encoded"`
+	} `json:"partialMerkleTree"`
+}
+
+func bitcoinBlocks(t *testing.T) []bitcoinBlock {
+	data, err := os.ReadFile("testdata/bitcoin_blocks.json")
+	assert.NoError(t, err)
+	var blocks []bitcoinBlock
+	assert.NoError(t, json.Unmarshal(data, &blocks))
+	return blocks
+}
+
+func parseTxids(t *testing.T, txids []string) [][]byte {
+	parsed := make([][]byte, len(txids))
+	for i, txid := range txids {
+		b, err := merkletree.ParseTxid(txid)
+		assert.NoError(t, err)
+		parsed[i] = b
+	}
+	return parsed
+}
+
+func TestBitcoinMerkleRoot(t *testing.T) {
+	for _, block := range bitcoinBlocks(t) {
+		root, err := merkletree.BitcoinMerkleRoot(parseTxids(t, block.Txids))
+		assert.NoError(t, err)
+		assert.Equal(t, block.MerkleRoot, merkletree.TxidString(root), fmt.Sprintf("unexpected root at block %d", block.Height))
+	}
+
+	// The last node of odd levels is paired with itself.
+	txids := parseTxids(t, bitcoinBlocks(t)[3].Txids[:3])
+	root, err := merkletree.BitcoinMerkleRoot(txids)
+	assert.NoError(t, err)
+	assert.Equal(t, "fa435470825de273081dcc706b25514c936fa6dc80ab965ce6970d68ddd0b553", merkletree.TxidString(root))
+
+	// Repeating the last transaction gives the same root, which is why it is rejected.
+	_, err = merkletree.BitcoinMerkleRoot(append(txids, txids[2]))
+	assert.EqualError(t, err, "the transactions contain a duplicated subtree (CVE-2012-2459)")
+
+	_, err = merkletree.BitcoinMerkleRoot(nil)
+	assert.Error(t, err)
+	_, err = merkletree.BitcoinMerkleRoot([][]byte{{1, 2, 3}})
+	assert.EqualError(t, err, "invalid txid length 3 at index 0")
+	_, err = merkletree.ParseTxid("1234")
+	assert.EqualError(t, err, `invalid txid "1234"`)
+}
+
+func TestPartialMerkleTree(t *testing.T) {
+	for _, block := range bitcoinBlocks(t) {
+		txids := parseTxids(t, block.Txids)
+		root, err := merkletree.ParseTxid(block.MerkleRoot)
+		assert.NoError(t, err)
+
+		for i := range txids {
+			matches := make([]bool, len(txids))
+			matches[i] = true
+			proof, err := merkletree.NewPartialMerkleTree(txids, matches)
+			assert.NoError(t, err)
+
+			verified, err := merkletree.VerifyBitcoinProof(txids[i], proof, root)
+			assert.NoError(t, err)
+			assert.True(t, verified, fmt.Sprintf("failed to verify proof at block %d transaction %d", block.Height, i))
+			verified, err = merkletree.VerifyBitcoinProof(txids[(i+1)%len(txids)], proof, root)
+			assert.NoError(t, err)
+			assert.Equal(t, len(txids) == 1, verified, fmt.Sprintf("verified unmatched transaction at block %d transaction %d", block.Height, i))
+
+			// The flags are padded to whole bytes on the wire.
+			encoded, err := proof.MarshalBinary()
+			assert.NoError(t, err)
+			var decoded merkletree.PartialMerkleTree
+			assert.NoError(t, decoded.UnmarshalBinary(encoded))
+			verified, err = merkletree.VerifyBitcoinProof(txids[i], &decoded, root)
+			assert.NoError(t, err)
+			assert.True(t, verified, fmt.Sprintf("failed to verify decoded proof at block %d transaction %d", block.Height, i))
+		}
+
+		if block.PartialMerkleTree != nil {
+			matches := make([]bool, len(txids))
+			for _, i := range block.PartialMerkleTree.Matches {
+				matches[i] = true
+			}
+			proof, err := merkletree.NewPartialMerkleTree(txids, matches)
+			assert.NoError(t, err)
+			encoded, err := proof.MarshalBinary()
+			assert.NoError(t, err)
+			assert.Equal(t, block.PartialMerkleTree.Encoded, hex.EncodeToString(encoded))
+		}
+	}
+}
+
+func TestPartialMerkleTreeMatches(t *testing.T) {
+	txids := leaves(11)
+	for i := range txids {
+		txids[i] = sha256.Hash(txids[i])
+	}
+	root, err := merkletree.BitcoinMerkleRoot(txids)
+	assert.NoError(t, err)
+
+	tests := [][]int{{}, {0}, {10}, {3, 4}, {0, 5, 9, 10}, {0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+	for i, test := range tests {
+		matches := make([]bool, len(txids))
+		var matched [][]byte
+		for _, index := range test {
+			matches[index] = true
+			matched = append(matched, txids[index])
+		}
+		proof, err := merkletree.NewPartialMerkleTree(txids, matches)
+		assert.NoError(t, err)
+
+		extracted, txs, indices, err := proof.ExtractMatches()
+		assert.NoError(t, err)
+		assert.Equal(t, root, extracted, fmt.Sprintf("unexpected root at test %d", i))
+		assert.Equal(t, matched, txs, fmt.Sprintf("unexpected matches at test %d", i))
+		assert.Len(t, indices, len(test))
+		for j, index := range indices {
+			assert.Equal(t, test[j], int(index), fmt.Sprintf("unexpected index at test %d", i))
+		}
+	}
+}
+
+func TestPartialMerkleTreeMalformed(t *testing.T) {
+	txids := parseTxids(t, bitcoinBlocks(t)[3].Txids)
+	proof, err := merkletree.NewPartialMerkleTree(txids, []bool{false, true, false, false})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		mutate func(p *merkletree.PartialMerkleTree)
+		err    string
+	}{
+		{ // 0
+			mutate: func(p *merkletree.PartialMerkleTree) { p.Transactions = 0 },
+			err:    "the partial merkle tree has no transactions",
+		},
+		{ // 1
+			mutate: func(p *merkletree.PartialMerkleTree) { p.Transactions = 1 << 20 },
+			err:    "the partial merkle tree has too many transactions",
+		},
+		{ // 2
+			mutate: func(p *merkletree.PartialMerkleTree) { p.Hashes = p.Hashes[:2] },
+			err:    "the partial merkle tree has too few hashes",
+		},
+		{ // 3
+			mutate: func(p *merkletree.PartialMerkleTree) { p.Hashes = append(p.Hashes, p.Hashes[0]) },
+			err:    "the partial merkle tree has unused hashes",
+		},
+		{ // 4
+			mutate: func(p *merkletree.PartialMerkleTree) { p.Flags = append(p.Flags, make([]bool, 8)...) },
+			err:    "the partial merkle tree has unused flags",
+		},
+		{ // 5
+			mutate: func(p *merkletree.PartialMerkleTree) { p.Flags = p.Flags[:2] },
+			err:    "the partial merkle tree has fewer flags than hashes",
+		},
+		{ // 6
+			mutate: func(p *merkletree.PartialMerkleTree) { p.Hashes[0] = p.Hashes[1] },
+			err:    "the partial merkle tree has a duplicated subtree (CVE-2012-2459)",
+		},
+		{ // 7
+			mutate: func(p *merkletree.PartialMerkleTree) { p.Hashes[0] = p.Hashes[0][:31] },
+			err:    "the proof hashes do not match the hash length",
+		},
+	}
+
+	for i, test := range tests {
+		p := *proof
+		p.Hashes = append([][]byte(nil), proof.Hashes...)
+		p.Flags = append([]bool(nil), proof.Flags...)
+		test.mutate(&p)
+		_, err := merkletree.VerifyBitcoinProof(txids[1], &p, txids[0])
+		assert.EqualError(t, err, test.err, fmt.Sprintf("unexpected error at test %d", i))
+	}
+
+	encoded, err := proof.MarshalBinary()
+	assert.NoError(t, err)
+	var decoded merkletree.PartialMerkleTree
+	assert.EqualError(t, decoded.UnmarshalBinary(encoded[:len(encoded)-1]), "the partial merkle tree has a wrong length")
+	assert.EqualError(t, decoded.UnmarshalBinary(encoded[:40]), "the partial merkle tree is truncated")
+}
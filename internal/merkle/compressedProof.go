@@ -0,0 +1,166 @@
+package merkletree
+
+import (
+	"errors"
+	"github.com/reactivejson/merkleTree/internal/merkle/hash"
+	"math/bits"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// CompressedProof is a proof of a Merkle tree whose siblings made only of padding are left out.
+// With ShapeZeroPad, a tree of 5 leaves is padded to 8: the siblings on the right edge are the padding hash of their
+// level, the hash of zero-filled leaves, which the verifier regenerates from the digest length and the leaf count
+// instead of receiving them. The other shapes don't pad their trees, so their proofs have no padding.
+type CompressedProof struct {
+	Hashes    [][]byte    // The hashes of the siblings that are not padding, from the leaf to the root
+	Padding   uint64      // Bit i is set when the sibling at level i, the leaves being level 0, is padding
+	Index     uint64      // The index of the input element for which the proof was generated
+	LeafCount uint64      // The number of leaves in the tree, telling which siblings are padding
+	Params    *TreeParams // How the tree was built, or nil if the verifier has to know it
+	Root      []byte      // The root of the tree the proof was generated from, or nil
+	Leaf      []byte      // The hash of the proven leaf, or nil
+}
+
+// GenerateCompressedProof generates the compressed proof for a piece of input.
+// If the input is present in the tree more than once, the proof of its first position is returned.
+func (t *MerkleTree) GenerateCompressedProof(data []byte) (*CompressedProof, error) {
+	indexes, err := t.dataIndexes(data)
+	if err != nil {
+		return nil, err
+	}
+	return t.GenerateCompressedProofAt(indexes[0])
+}
+
+// GenerateCompressedProofAt generates the compressed proof for the leaf at the given index.
+func (t *MerkleTree) GenerateCompressedProofAt(index uint64) (*CompressedProof, error) {
+	proof, err := t.GenerateMProofAt(index)
+	if err != nil {
+		return nil, err
+	}
+
+	compressed := &CompressedProof{
+		Index:     proof.Index,
+		LeafCount: proof.LeafCount,
+		Params:    proof.Params,
+		Root:      proof.Root,
+		Leaf:      proof.Leaf,
+	}
+	for level, h := range proof.Hashes {
+		if t.shape == ShapeZeroPad && isPadding(index, proof.LeafCount, level) {
+			compressed.Padding |= 1 << uint(level)
+			continue
+		}
+		compressed.Hashes = append(compressed.Hashes, h)
+	}
+	return compressed, nil
+}
+
+// isPadding reports whether the sibling at the given level of the leaf at the index covers only padding leaves.
+func isPadding(index, leafCount uint64, level int) bool {
+	// The sibling covers the leaves from sibling<<level, which is padding past the last leaf.
+	sibling := index>>uint(level) ^ 1
+	return leafCount > 0 && sibling > (leafCount-1)>>uint(level)
+}
+
+// Decompress returns the full proof, regenerating the padding siblings with the hash type and options the tree was
+// built with. A padding bitmap that doesn't match the leaf count is an error.
+func (p *CompressedProof) Decompress(hashType hash.HashType, opts ...Option) (*MerkleProof, error) {
+	if p == nil {
+		return nil, ErrNilProof
+	}
+	if hashType == nil {
+		return nil, ErrNilHashType
+	}
+	cfg := newConfig(opts)
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	levels := len(p.Hashes) + bits.OnesCount64(p.Padding)
+	if levels > maxProofDepth {
+		return nil, ErrProofTooDeep
+	}
+	if levels < 64 && p.Padding>>uint(levels) != 0 {
+		return nil, errPaddingLevels
+	}
+	if p.Padding != 0 && cfg.shape != ShapeZeroPad {
+		return nil, errors.New("only zero-padded trees have padding")
+	}
+	if p.LeafCount != 0 && cfg.shape == ShapeZeroPad {
+		// The tree of the leaf count has as many levels as the bits of its last index.
+		if levels != bits.Len64(p.LeafCount-1) {
+			return nil, errPaddingMismatch
+		}
+		for level := 0; level < levels; level++ {
+			if isPadding(p.Index, p.LeafCount, level) != (p.Padding&(1<<uint(level)) != 0) {
+				return nil, errPaddingMismatch
+			}
+		}
+	}
+
+	hasher := cfg.hasher(hashType)
+	hashes := make([][]byte, 0, levels)
+	remaining := p.Hashes
+	// pad is the padding hash of the current level, the hash of zero-filled leaves.
+	pad := make([]byte, hashType.HashLength())
+	for level := 0; level < levels; level++ {
+		if p.Padding&(1<<uint(level)) != 0 {
+			hashes = append(hashes, pad)
+		} else {
+			hashes = append(hashes, remaining[0])
+			remaining = remaining[1:]
+		}
+		if p.Padding>>uint(level+1) != 0 {
+			pad = hasher.branch(pad, pad)
+		}
+	}
+
+	return &MerkleProof{
+		Hashes:    hashes,
+		Index:     p.Index,
+		LeafCount: p.LeafCount,
+		Params:    p.Params,
+		Root:      p.Root,
+		Leaf:      p.Leaf,
+	}, nil
+}
+
+// VerifyCompressedProof verifies a compressed proof for a piece of input against the root, as VerifyMProof does
+// once the padding siblings are regenerated.
+func VerifyCompressedProof(data []byte, proof *CompressedProof, root []byte, hashType hash.HashType, opts ...Option) (bool, error) {
+	full, err := proof.Decompress(hashType, opts...)
+	if err != nil {
+		return false, err
+	}
+	return VerifyMProof(data, full, root, hashType, opts...)
+}
+
+// MarshalBinary encodes the compressed proof as MerkleProof does, with a flag telling that the padding bitmap
+// follows the number of hashes as an unsigned varint. A compressed proof without padding has the encoding of the
+// full proof.
+func (p *CompressedProof) MarshalBinary() ([]byte, error) {
+	proof := MerkleProof{Hashes: p.Hashes, Index: p.Index, LeafCount: p.LeafCount, Params: p.Params, Root: p.Root, Leaf: p.Leaf}
+	return proof.marshalBinary(p.Padding)
+}
+
+// UnmarshalBinary decodes a compressed proof encoded by MarshalBinary, or a full proof encoded by
+// MerkleProof.MarshalBinary.
+func (p *CompressedProof) UnmarshalBinary(data []byte) error {
+	proof, padding, err := unmarshalProof(data)
+	if err != nil {
+		return err
+	}
+	*p = CompressedProof{
+		Hashes:    proof.Hashes,
+		Padding:   padding,
+		Index:     proof.Index,
+		LeafCount: proof.LeafCount,
+		Params:    proof.Params,
+		Root:      proof.Root,
+		Leaf:      proof.Leaf,
+	}
+	return nil
+}
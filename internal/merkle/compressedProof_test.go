@@ -0,0 +1,132 @@
+package merkletree_test
+
+import (
+	"fmt"
+	merkletree "github.com/reactivejson/merkleTree/internal/merkle"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressedProof(t *testing.T) {
+	tests := [][]merkletree.Option{
+		nil,
+		{merkletree.WithRFC6962Prefixes()},
+		{merkletree.WithSortedPairs()},
+		{merkletree.WithShape(merkletree.ShapePromoteOdd)},
+	}
+
+	for i, opts := range tests {
+		for n := 1; n <= 17; n++ {
+			data := leaves(n)
+			tree, err := merkletree.NewTree(data, blake3, opts...)
+			assert.NoError(t, err)
+			for j, d := range data {
+				full, err := tree.GenerateMProofAt(uint64(j))
+				assert.NoError(t, err)
+				compressed, err := tree.GenerateCompressedProofAt(uint64(j))
+				assert.NoError(t, err)
+
+				decompressed, err := compressed.Decompress(blake3, opts...)
+				assert.NoError(t, err)
+				assert.Equal(t, full, decompressed, fmt.Sprintf("unexpected proof at test %d with %d leaves input %d", i, n, j))
+				verified, err := merkletree.VerifyCompressedProof(d, compressed, tree.MerkleRoot(), blake3, opts...)
+				assert.NoError(t, err)
+				assert.True(t, verified, fmt.Sprintf("failed to verify proof at test %d with %d leaves input %d", i, n, j))
+
+				encoded, err := compressed.MarshalBinary()
+				assert.NoError(t, err)
+				var decoded merkletree.CompressedProof
+				assert.NoError(t, decoded.UnmarshalBinary(encoded))
+				assert.Equal(t, compressed, &decoded, fmt.Sprintf("unexpected decoded proof at test %d with %d leaves input %d", i, n, j))
+			}
+		}
+	}
+}
+
+func TestCompressedProofSize(t *testing.T) {
+	// 5 leaves are padded to 8: the last leaf has a padding leaf and a node of two padding leaves as siblings.
+	tree, err := merkletree.NewTree(leaves(5), blake3)
+	assert.NoError(t, err)
+	compressed, err := tree.GenerateCompressedProof([]byte("leaf-4"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0b011), compressed.Padding)
+	assert.Len(t, compressed.Hashes, 1)
+
+	full, err := tree.GenerateMProofAt(4)
+	assert.NoError(t, err)
+	fullEncoded, err := full.MarshalBinary()
+	assert.NoError(t, err)
+	encoded, err := compressed.MarshalBinary()
+	assert.NoError(t, err)
+	assert.Equal(t, len(fullEncoded)-2*32+1, len(encoded))
+
+	// The proofs of the first leaves have no padding, and encode as full proofs.
+	compressed, err = tree.GenerateCompressedProofAt(0)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), compressed.Padding)
+	full, err = tree.GenerateMProofAt(0)
+	assert.NoError(t, err)
+	encoded, err = compressed.MarshalBinary()
+	assert.NoError(t, err)
+	fullEncoded, err = full.MarshalBinary()
+	assert.NoError(t, err)
+	assert.Equal(t, fullEncoded, encoded)
+}
+
+func TestCompressedProofErrors(t *testing.T) {
+	tree, err := merkletree.NewTree(leaves(5), blake3)
+	assert.NoError(t, err)
+	root := tree.MerkleRoot()
+	proof, err := tree.GenerateCompressedProofAt(4)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		proof merkletree.CompressedProof
+		opts  []merkletree.Option
+		err   string
+	}{
+		{ // 0
+			proof: merkletree.CompressedProof{Hashes: proof.Hashes, Padding: 0b101, Index: 4, LeafCount: 5},
+			err:   "the proof padding does not match its leaf count",
+		},
+		{ // 1
+			proof: merkletree.CompressedProof{Hashes: proof.Hashes, Padding: 0b1001, Index: 4, LeafCount: 5},
+			err:   "the proof padding is past its levels",
+		},
+		{ // 2
+			proof: merkletree.CompressedProof{Hashes: proof.Hashes, Padding: 0b111, Index: 4, LeafCount: 5},
+			err:   "the proof padding does not match its leaf count",
+		},
+		{ // 3
+			proof: merkletree.CompressedProof{Hashes: proof.Hashes, Padding: 0b011, Index: 4, LeafCount: 5},
+			opts:  []merkletree.Option{merkletree.WithShape(merkletree.ShapePromoteOdd)},
+			err:   "only zero-padded trees have padding",
+		},
+		{ // 4
+			proof: merkletree.CompressedProof{Hashes: append(proof.Hashes, make([][]byte, 62)...), Padding: 0b011, Index: 4},
+			err:   "the proof has too many hashes",
+		},
+	}
+
+	for i, test := range tests {
+		verified, err := merkletree.VerifyCompressedProof([]byte("leaf-4"), &test.proof, root, blake3, test.opts...)
+		assert.EqualError(t, err, test.err, fmt.Sprintf("unexpected error at test %d", i))
+		assert.False(t, verified, fmt.Sprintf("verified proof at test %d", i))
+	}
+	_, err = merkletree.VerifyCompressedProof([]byte("leaf-4"), nil, root, blake3)
+	assert.ErrorIs(t, err, merkletree.ErrNilProof)
+
+	// Without a leaf count, the padding is taken as is.
+	proof.LeafCount = 0
+	proof.Params = nil
+	verified, err := merkletree.VerifyCompressedProof([]byte("leaf-4"), proof, root, blake3)
+	assert.NoError(t, err)
+	assert.True(t, verified)
+
+	// A full proof can't be decoded from a compressed one.
+	encoded, err := proof.MarshalBinary()
+	assert.NoError(t, err)
+	var full merkletree.MerkleProof
+	assert.EqualError(t, full.UnmarshalBinary(encoded), "the proof is compressed")
+}
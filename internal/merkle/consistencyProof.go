@@ -0,0 +1,150 @@
+package merkletree
+
+import (
+	"bytes"
+	"errors"
+	"github.com/reactivejson/merkleTree/internal/merkle/hash"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// RootAt returns the Merkle Tree Hash, as defined in RFC 6962 and RFC 9162, of the first size leaves of the tree.
+// In that definition a tree of n leaves is split at the largest power of 2 smaller than n, instead of being padded.
+// RootAt(size) is the same as MerkleRoot when size is the number of leaves and the tree shape is ShapeRFC6962 or
+// ShapePromoteOdd; with the other shapes they are only the same when size is a power of 2.
+// With WithRFC6962Prefixes and SHA-256, RootAt(size) is the root hash of a Certificate Transparency log of that size.
+func (t *MerkleTree) RootAt(size uint64) ([]byte, error) {
+	if size == 0 || size > uint64(len(t.data)) {
+		return nil, errors.New("invalid tree size")
+	}
+	return t.subtreeHash(0, size), nil
+}
+
+// subtreeHash returns the RFC 6962 Merkle Tree Hash of the leaves [start, end).
+// Subtrees of a power of 2 leaves aligned on their size are nodes of the tree already, so only the nodes on the
+// right edge of the range are hashed.
+func (t *MerkleTree) subtreeHash(start, end uint64) []byte {
+	size := end - start
+	if size&(size-1) == 0 && start%size == 0 {
+		width := uint64(len(t.nodes) / 2)
+		index := width + start
+		for s := size; s > 1; s /= 2 {
+			index /= 2
+		}
+		return t.nodes[index]
+	}
+	k := largestPowerOf2Below(size)
+	return t.hasher().node(t.subtreeHash(start, start+k), t.subtreeHash(start+k, end))
+}
+
+// ConsistencyProof generates the proof that the tree with the first oldSize leaves is a prefix of the tree with the
+// first newSize leaves, following the algorithm of RFC 9162 section 2.1.4. The roots it relates are the ones returned
+// by RootAt, and both sizes must be within the current size of the tree.
+func (t *MerkleTree) ConsistencyProof(oldSize, newSize uint64) ([][]byte, error) {
+	if oldSize == 0 || oldSize > newSize || newSize > uint64(len(t.data)) {
+		return nil, errors.New("invalid tree size")
+	}
+	if oldSize == newSize {
+		return [][]byte{}, nil
+	}
+	return t.subproof(oldSize, 0, newSize, true), nil
+}
+
+// subproof is SUBPROOF(m, D[start:end], b) of RFC 9162: the nodes needed to compute the root of the leaves
+// [start, end) from the root of the first m of them. complete tells whether that root is already known to the verifier.
+func (t *MerkleTree) subproof(m, start, end uint64, complete bool) [][]byte {
+	n := end - start
+	if m == n {
+		if complete {
+			return nil
+		}
+		return [][]byte{t.subtreeHash(start, end)}
+	}
+
+	k := largestPowerOf2Below(n)
+	if m <= k {
+		return append(t.subproof(m, start, start+k, complete), t.subtreeHash(start+k, end))
+	}
+	return append(t.subproof(m-k, start+k, end, false), t.subtreeHash(start, start+k))
+}
+
+// VerifyConsistency verifies that the tree of oldSize leaves with root oldRoot is a prefix of the tree of newSize leaves
+// with root newRoot, following the algorithm of RFC 9162 section 2.1.4.2.
+// The options must match the ones the tree was built with, e.g. WithRFC6962Prefixes.
+//
+// This returns true if the proof is verified, otherwise false. An error is returned for invalid sizes or a malformed proof.
+func VerifyConsistency(oldRoot, newRoot []byte, oldSize, newSize uint64, proof [][]byte, hashType hash.HashType, opts ...Option) (bool, error) {
+	if hashType == nil {
+		return false, ErrNilHashType
+	}
+	cfg := newConfig(opts)
+	if err := cfg.validate(); err != nil {
+		return false, err
+	}
+	if oldSize == 0 || oldSize > newSize {
+		return false, errors.New("invalid tree size")
+	}
+	if err := checkLengths(hashType, oldRoot, proof); err != nil {
+		return false, err
+	}
+	if err := checkLengths(hashType, newRoot); err != nil {
+		return false, err
+	}
+	if oldSize == newSize {
+		if len(proof) != 0 {
+			return false, errors.New("too many hashes in the proof")
+		}
+		return bytes.Equal(oldRoot, newRoot), nil
+	}
+	if len(proof) == 0 {
+		return false, errors.New("not enough hashes in the proof")
+	}
+	hasher := cfg.hasher(hashType)
+
+	// When the old tree is complete its root is the first node of the path, and the proof leaves it out.
+	if oldSize&(oldSize-1) == 0 {
+		proof = append([][]byte{oldRoot}, proof...)
+	}
+
+	fn, sn := oldSize-1, newSize-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+
+	fr, sr := proof[0], proof[0]
+	for _, c := range proof[1:] {
+		if sn == 0 {
+			return false, errors.New("too many hashes in the proof")
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = hasher.node(c, fr)
+			sr = hasher.node(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = hasher.node(sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return false, errors.New("not enough hashes in the proof")
+	}
+
+	return bytes.Equal(fr, oldRoot) && bytes.Equal(sr, newRoot), nil
+}
+
+// largestPowerOf2Below returns the largest power of 2 smaller than n, for n > 1.
+func largestPowerOf2Below(n uint64) uint64 {
+	k := uint64(1)
+	for k*2 < n {
+		k *= 2
+	}
+	return k
+}
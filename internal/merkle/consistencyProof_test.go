@@ -0,0 +1,167 @@
+package merkletree_test
+
+import (
+	"fmt"
+	merkletree "github.com/reactivejson/merkleTree/internal/merkle"
+	"github.com/reactivejson/merkleTree/internal/merkle/hash"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// sha256 is the hashing method used by Certificate Transparency logs.
+var sha256 = hash.NewSHA256()
+
+// ctLeaves are the leaves of the reference Certificate Transparency test vectors.
+var ctLeaves = [][]byte{
+	stringToByte(""),
+	stringToByte("00"),
+	stringToByte("10"),
+	stringToByte("2021"),
+	stringToByte("3031"),
+	stringToByte("40414243"),
+	stringToByte("5051525354555657"),
+	stringToByte("606162636465666768696a6b6c6d6e6f"),
+}
+
+// ctRoots are the roots of the trees made of the first 1 to 8 ctLeaves.
+var ctRoots = [][]byte{
+	stringToByte("6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d"),
+	stringToByte("fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125"),
+	stringToByte("aeb6bcfe274b70a14fb067a5e5578264db0fa9b51af5e0ba159158f329e06e77"),
+	stringToByte("d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7"),
+	stringToByte("4e3bbb1f7b478dcfe71fb631631519a3bca12c9aefca1612bfce4c13a86264d4"),
+	stringToByte("76e67dadbcdf1e10e1b74ddc608abd2f98dfb16fbce75277b5232a127f2087ef"),
+	stringToByte("ddb89be403809e325750d3d263cd78929c2942b7942a34b77e122c9594a74c8c"),
+	stringToByte("5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328"),
+}
+
+var consistencyTests = []struct {
+	oldSize uint64
+	newSize uint64
+	proof   [][]byte
+}{
+	{
+		oldSize: 1,
+		newSize: 1,
+		proof:   [][]byte{},
+	},
+	{
+		oldSize: 1,
+		newSize: 8,
+		proof: [][]byte{
+			stringToByte("96a296d224f285c67bee93c30f8a309157f0daa35dc5b87e410b78630a09cfc7"),
+			stringToByte("5f083f0a1a33ca076a95279832580db3e0ef4584bdff1f54c8a360f50de3031e"),
+			stringToByte("6b47aaf29ee3c2af9af889bc1fb9254dabd31177f16232dd6aab035ca39bf6e4"),
+		},
+	},
+	{
+		oldSize: 6,
+		newSize: 8,
+		proof: [][]byte{
+			stringToByte("0ebc5d3437fbe2db158b9f126a1d118e308181031d0a949f8dededebc558ef6a"),
+			stringToByte("ca854ea128ed050b41b35ffc1b87b8eb2bde461e9e3b5596ece6b9d5975a0ae0"),
+			stringToByte("d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7"),
+		},
+	},
+	{
+		oldSize: 2,
+		newSize: 5,
+		proof: [][]byte{
+			stringToByte("5f083f0a1a33ca076a95279832580db3e0ef4584bdff1f54c8a360f50de3031e"),
+			stringToByte("bc1a0643b12e4d2d7c77918f44e0f4f79a838b6cf9ec5b5c283e1f4d88599e6b"),
+		},
+	},
+}
+
+func TestRootAt(t *testing.T) {
+	tree, err := merkletree.NewTree(ctLeaves, sha256, merkletree.WithRFC6962Prefixes())
+	assert.NoError(t, err)
+
+	for i, root := range ctRoots {
+		res, err := tree.RootAt(uint64(i + 1))
+		assert.NoError(t, err)
+		assert.Equal(t, root, res, fmt.Sprintf("unexpected root for size %d", i+1))
+	}
+	assert.Equal(t, ctRoots[7], tree.MerkleRoot())
+
+	_, err = tree.RootAt(0)
+	assert.EqualError(t, err, "invalid tree size")
+	_, err = tree.RootAt(9)
+	assert.EqualError(t, err, "invalid tree size")
+}
+
+func TestConsistencyProof(t *testing.T) {
+	tree, err := merkletree.NewTree(ctLeaves, sha256, merkletree.WithRFC6962Prefixes())
+	assert.NoError(t, err)
+
+	for i, test := range consistencyTests {
+		proof, err := tree.ConsistencyProof(test.oldSize, test.newSize)
+		assert.NoError(t, err)
+		assert.Equal(t, test.proof, proof, fmt.Sprintf("unexpected proof at test %d", i))
+
+		ok, err := merkletree.VerifyConsistency(ctRoots[test.oldSize-1], ctRoots[test.newSize-1], test.oldSize, test.newSize, proof, sha256, merkletree.WithRFC6962Prefixes())
+		assert.NoError(t, err)
+		assert.True(t, ok, fmt.Sprintf("failed to verify proof at test %d", i))
+	}
+}
+
+func TestConsistencyProofAllSizes(t *testing.T) {
+	tree, err := merkletree.NewTree(ctLeaves, sha256, merkletree.WithRFC6962Prefixes())
+	assert.NoError(t, err)
+
+	for oldSize := uint64(1); oldSize <= 8; oldSize++ {
+		for newSize := oldSize; newSize <= 8; newSize++ {
+			proof, err := tree.ConsistencyProof(oldSize, newSize)
+			assert.NoError(t, err)
+
+			ok, err := merkletree.VerifyConsistency(ctRoots[oldSize-1], ctRoots[newSize-1], oldSize, newSize, proof, sha256, merkletree.WithRFC6962Prefixes())
+			assert.NoError(t, err)
+			assert.True(t, ok, fmt.Sprintf("failed to verify proof from %d to %d", oldSize, newSize))
+
+			// The proof does not hold for another old root.
+			if oldSize < newSize {
+				ok, _ = merkletree.VerifyConsistency(ctRoots[newSize-1], ctRoots[newSize-1], oldSize, newSize, proof, sha256, merkletree.WithRFC6962Prefixes())
+				assert.False(t, ok, fmt.Sprintf("verified wrong old root from %d to %d", oldSize, newSize))
+			}
+		}
+	}
+}
+
+func TestConsistencyProofAfterAppend(t *testing.T) {
+	tree, err := merkletree.NewTree(ctLeaves[:3], sha256, merkletree.WithRFC6962Prefixes())
+	assert.NoError(t, err)
+	oldRoot, err := tree.RootAt(3)
+	assert.NoError(t, err)
+
+	tree.Append(ctLeaves[3:]...)
+	proof, err := tree.ConsistencyProof(3, 8)
+	assert.NoError(t, err)
+	ok, err := merkletree.VerifyConsistency(oldRoot, tree.MerkleRoot(), 3, 8, proof, sha256, merkletree.WithRFC6962Prefixes())
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestConsistencyProofErrors(t *testing.T) {
+	tree, err := merkletree.NewTree(ctLeaves, sha256, merkletree.WithRFC6962Prefixes())
+	assert.NoError(t, err)
+
+	_, err = tree.ConsistencyProof(0, 3)
+	assert.EqualError(t, err, "invalid tree size")
+	_, err = tree.ConsistencyProof(4, 3)
+	assert.EqualError(t, err, "invalid tree size")
+	_, err = tree.ConsistencyProof(3, 9)
+	assert.EqualError(t, err, "invalid tree size")
+
+	_, err = merkletree.VerifyConsistency(ctRoots[1], ctRoots[4], 2, 5, nil, sha256, merkletree.WithRFC6962Prefixes())
+	assert.EqualError(t, err, "not enough hashes in the proof")
+	_, err = merkletree.VerifyConsistency(ctRoots[1], ctRoots[4], 2, 5, consistencyTests[3].proof[:1], sha256, merkletree.WithRFC6962Prefixes())
+	assert.EqualError(t, err, "not enough hashes in the proof")
+	_, err = merkletree.VerifyConsistency(ctRoots[0], ctRoots[0], 1, 1, consistencyTests[3].proof, sha256, merkletree.WithRFC6962Prefixes())
+	assert.EqualError(t, err, "too many hashes in the proof")
+}
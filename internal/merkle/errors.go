@@ -0,0 +1,64 @@
+package merkletree
+
+import "errors"
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// The errors of the verification of proofs. A proof that is well formed but doesn't prove the input against the root
+// is not an error: the verification returns false and a nil error. Every error telling that a proof can't be verified
+// whatever the root, such as ErrHashLength, matches ErrMalformedProof with errors.Is, and every error telling that
+// it was built with other tree parameters than the ones it is verified with matches ErrParamsMismatch.
+var (
+	// ErrNilProof is returned when verifying a nil proof.
+	ErrNilProof = errors.New("the proof is nil")
+	// ErrNilHashType is returned when verifying a proof with a nil hash type.
+	ErrNilHashType = errors.New("the hash type is nil")
+	// ErrMalformedProof is matched by the errors of proofs that can't be verified.
+	ErrMalformedProof = errors.New("malformed proof")
+	// ErrParamsMismatch is matched by the errors of proofs carrying other tree parameters than the verification.
+	ErrParamsMismatch = errors.New("the proof was built with other tree parameters")
+
+	// ErrRootLength is returned when the root isn't a digest of the hash type.
+	ErrRootLength = malformed("the root does not match the hash length")
+	// ErrHashLength is returned when a hash of a proof isn't a digest of the hash type.
+	ErrHashLength = malformed("the proof hashes do not match the hash length")
+	// ErrIndexOutOfRange is returned when the index of a proof can't be reached in as many levels as it has hashes,
+	// or is past the number of leaves of the tree.
+	ErrIndexOutOfRange = malformed("index out of bounds")
+	// ErrProofTooDeep is returned when a proof has more hashes than a tree can have levels.
+	ErrProofTooDeep = malformed("the proof has too many hashes")
+
+	errNoLeafCount       = malformed("the proof has no leaf count")
+	errLeafCountTooLarge = malformed("the proof leaf count is too large")
+	errNotDigest         = malformed("the root or proof hashes are not digests of the hash type")
+	errNotEnoughHashes   = malformed("not enough hashes in the proof")
+	errTooManyHashes     = malformed("too many hashes in the proof")
+	errPaddingLevels     = malformed("the proof padding is past its levels")
+	errPaddingMismatch   = malformed("the proof padding does not match its leaf count")
+)
+
+// verificationError is an error of the verification of a proof, matched by errors.Is with the kind of error it is.
+type verificationError struct {
+	kind error
+	msg  string
+}
+
+func malformed(msg string) error {
+	return &verificationError{kind: ErrMalformedProof, msg: msg}
+}
+
+func mismatch(msg string) error {
+	return &verificationError{kind: ErrParamsMismatch, msg: msg}
+}
+
+func (e *verificationError) Error() string {
+	return e.msg
+}
+
+// Is reports whether the error is of the kind of target.
+func (e *verificationError) Is(target error) bool {
+	return target == e.kind
+}
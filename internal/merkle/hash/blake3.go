@@ -1,6 +1,10 @@
 package hash
 
 import (
+	"fmt"
+	gohash "hash"
+	"sync"
+
 	"lukechampine.com/blake3"
 )
 
@@ -10,37 +14,175 @@ import (
  */
 const _blake3hashlength = 32
 
+// The digest lengths BLAKE3 can be built with: shorter digests are too easy to collide, and longer ones don't fit the
+// single-block output of blake3.Sum512.
+const (
+	_blake3minlength = 16
+	_blake3maxlength = 64
+)
+
 // BLAKE3 is the Blake3 hashing method.
-type BLAKE3 struct{}
+// Besides the default mode it supports the keyed and key derivation modes of BLAKE3, in which every hash depends on
+// a secret key or a context string: roots of trees built in those modes can't be computed or correlated without them.
+// Its output being extendable, the length of its digests can be chosen with WithLength.
+type BLAKE3 struct {
+	// length is the length of the digests, or 0 for the default length.
+	length int
+	// key is the key of the keyed mode, or nil.
+	key []byte
+	// keyed holds reusable hashers of the keyed mode, so that the key isn't loaded again for every hash.
+	keyed *sync.Pool
+	// derive tells whether the hash is in key derivation mode, with context as the context string.
+	derive  bool
+	context string
+}
 
 // New creates a new BLAKE3 hashing method.
 func NewBlake3() *BLAKE3 {
 	return &BLAKE3{}
 }
 
+// NewBlake3WithLength creates a new BLAKE3 hashing method with digests of the given length, from 16 to 64 bytes.
+func NewBlake3WithLength(length int) (*BLAKE3, error) {
+	return NewBlake3().withLength(length)
+}
+
+// NewBlake3Keyed creates a new BLAKE3 hashing method in keyed mode: every hash is a MAC of its input under the key.
+func NewBlake3Keyed(key [32]byte) *BLAKE3 {
+	h := &BLAKE3{key: key[:]}
+	h.keyed = h.newKeyedPool()
+	return h
+}
+
+// newKeyedPool returns a pool of hashers of the keyed mode, with the key and digest length of h.
+func (h *BLAKE3) newKeyedPool() *sync.Pool {
+	key, length := h.key, h.HashLength()
+	return &sync.Pool{New: func() interface{} { return blake3.New(length, key) }}
+}
+
+// NewBlake3DeriveKey creates a new BLAKE3 hashing method in key derivation mode: every hash is the key derived from
+// its input in the given context. The context should be hardcoded, globally unique and application-specific.
+// This mode hashes the context again for every input, which makes it about twice as slow as the other ones: the
+// blake3 package has no way to reuse the key derived from the context.
+func NewBlake3DeriveKey(context string) *BLAKE3 {
+	return &BLAKE3{derive: true, context: context}
+}
+
+// Name returns the identifier of BLAKE3 in the registry.
+// The keyed and key derivation modes have identifiers of their own, which are not registered: the key or context
+// can't be recovered from the name.
+func (h *BLAKE3) Name() string {
+	switch {
+	case h.key != nil:
+		return "blake3-keyed"
+	case h.derive:
+		return "blake3-derive-key"
+	default:
+		return "blake3"
+	}
+}
+
 // HashLength returns the length of hashes generated by Hash() in bytes.
 func (h *BLAKE3) HashLength() int {
-	return _blake3hashlength
+	if h.length == 0 {
+		return _blake3hashlength
+	}
+	return h.length
+}
+
+// WithLength returns the same BLAKE3 hashing method, in the same mode, with digests of the given length.
+// The length must be from 16 to 64 bytes. A shorter digest is a prefix of a longer one for the same input.
+func (h *BLAKE3) WithLength(length int) (HashType, error) {
+	return h.withLength(length)
+}
+
+// withLength is WithLength, returning the concrete type.
+func (h *BLAKE3) withLength(length int) (*BLAKE3, error) {
+	if length < _blake3minlength || length > _blake3maxlength {
+		return nil, fmt.Errorf("the length of blake3 hashes must be between %d and %d bytes", _blake3minlength, _blake3maxlength)
+	}
+	h2 := *h
+	h2.length = length
+	if h2.key != nil {
+		h2.keyed = h2.newKeyedPool()
+	}
+	return &h2, nil
+}
+
+// NewHasher returns a hash.Hash computing the BLAKE3 hash of the data written to it.
+func (h *BLAKE3) NewHasher() gohash.Hash {
+	if h.key != nil {
+		return blake3.New(h.HashLength(), h.key)
+	}
+	return &blake3Hasher{h: h}
 }
 
 // Hash generates a BLAKE2b hash from input byte arrays.
 func (h *BLAKE3) Hash(data ...[]byte) []byte {
-	var hash [_blake3hashlength]byte
 	if len(data) == 1 {
-		hash = blake3.Sum256(data[0])
-	} else {
-		concatDataLen := 0
-		for _, d := range data {
-			concatDataLen += len(d)
-		}
-		concatData := make([]byte, concatDataLen)
-		curOffset := 0
-		for _, d := range data {
-			copy(concatData[curOffset:], d)
-			curOffset += len(d)
-		}
-		hash = blake3.Sum256(concatData)
-	}
-
-	return hash[:]
+		return h.sum(nil, data[0])
+	}
+	concatDataLen := 0
+	for _, d := range data {
+		concatDataLen += len(d)
+	}
+	concatData := make([]byte, concatDataLen)
+	curOffset := 0
+	for _, d := range data {
+		copy(concatData[curOffset:], d)
+		curOffset += len(d)
+	}
+	return h.sum(nil, concatData)
+}
+
+// sum hashes the input in the mode of the hash type, and appends the digest to dst.
+func (h *BLAKE3) sum(dst, data []byte) []byte {
+	var hash [_blake3maxlength]byte
+	switch {
+	case h.key != nil:
+		keyed := h.keyed.Get().(*blake3.Hasher)
+		keyed.Reset()
+		keyed.Write(data)
+		keyed.Sum(hash[:0])
+		h.keyed.Put(keyed)
+	case h.derive:
+		blake3.DeriveKey(hash[:h.HashLength()], h.context, data)
+	default:
+		hash = blake3.Sum512(data)
+	}
+	return append(dst, hash[:h.HashLength()]...)
+}
+
+// blake3Hasher is a hash.Hash that buffers its input and hashes it at once, in the mode of its hash type.
+// Tree nodes are short enough for blake3.Sum512 to hash them in a single block, which is much faster than feeding them to
+// an incremental blake3.Hasher; the buffer is kept across resets, so reusing the hasher does not allocate.
+type blake3Hasher struct {
+	h   *BLAKE3
+	buf []byte
+}
+
+// Write adds data to the running hash.
+func (d *blake3Hasher) Write(p []byte) (int, error) {
+	d.buf = append(d.buf, p...)
+	return len(p), nil
+}
+
+// Sum appends the hash of the data written so far to b.
+func (d *blake3Hasher) Sum(b []byte) []byte {
+	return d.h.sum(b, d.buf)
+}
+
+// Reset empties the running hash.
+func (d *blake3Hasher) Reset() {
+	d.buf = d.buf[:0]
+}
+
+// Size returns the length of the hash in bytes.
+func (d *blake3Hasher) Size() int {
+	return d.h.HashLength()
+}
+
+// BlockSize returns the block size of BLAKE3 in bytes.
+func (d *blake3Hasher) BlockSize() int {
+	return 64
 }
@@ -0,0 +1,139 @@
+package hash
+
+import "encoding/binary"
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+const (
+	// blake3Lanes is the number of inputs compressed at once by compress8SIMD.
+	blake3Lanes = 8
+	// blake3BlockLen and blake3ChunkLen are the sizes of the blocks and chunks of BLAKE3 in bytes. HashBatch only
+	// batches inputs of a single chunk, which covers tree nodes and most leaves.
+	blake3BlockLen = 64
+	blake3ChunkLen = 1024
+)
+
+// The domain flags of BLAKE3.
+const (
+	blake3ChunkStart        = 1 << 0
+	blake3ChunkEnd          = 1 << 1
+	blake3Root              = 1 << 3
+	blake3KeyedHash         = 1 << 4
+	blake3DeriveKeyContext  = 1 << 5
+	blake3DeriveKeyMaterial = 1 << 6
+)
+
+// blake3IV is the initialization vector of BLAKE3, the key words of the default mode.
+var blake3IV = [8]uint32{0x6a09e667, 0xbb67ae85, 0x3c6ef372, 0xa54ff53a, 0x510e527f, 0x9b05688c, 0x1f83d9ab, 0x5be0cd19}
+
+// HashBatch hashes every input, writing their digests one after the other to dst, which must be
+// len(inputs)*HashLength() bytes long. Inputs of the same length, up to 1024 bytes, are hashed eight at a time with
+// SIMD instructions when the CPU supports them; other inputs, or all of them without SIMD, are hashed one at a time.
+func (h *BLAKE3) HashBatch(dst []byte, inputs [][]byte) {
+	size := h.HashLength()
+	ok := useSIMD
+	var key [8]uint32
+	var flags uint32
+	if ok {
+		key, flags, ok = h.batchKey()
+	}
+	for i := range inputs {
+		if !ok || len(inputs[i]) != len(inputs[0]) || len(inputs[i]) > blake3ChunkLen {
+			ok = false
+			break
+		}
+	}
+	if !ok {
+		for i, input := range inputs {
+			h.sum(dst[i*size:i*size], input)
+		}
+		return
+	}
+
+	for from := 0; from < len(inputs); from += blake3Lanes {
+		to := from + blake3Lanes
+		if to > len(inputs) {
+			to = len(inputs)
+		}
+		hashLanes(dst[from*size:to*size], inputs[from:to], &key, flags, size)
+	}
+}
+
+// batchKey returns the key words and flags of the mode of the hash type, or false if the inputs can't be batched.
+func (h *BLAKE3) batchKey() ([8]uint32, uint32, bool) {
+	var key [8]uint32
+	switch {
+	case h.key != nil:
+		for i := range key {
+			key[i] = binary.LittleEndian.Uint32(h.key[4*i:])
+		}
+		return key, blake3KeyedHash, true
+	case h.derive:
+		// The key of the derived keys is the hash of the context, in its own mode.
+		if len(h.context) > blake3ChunkLen {
+			return key, 0, false
+		}
+		var contextKey [32]byte
+		hashLanes(contextKey[:], [][]byte{[]byte(h.context)}, &blake3IV, blake3DeriveKeyContext, len(contextKey))
+		for i := range key {
+			key[i] = binary.LittleEndian.Uint32(contextKey[4*i:])
+		}
+		return key, blake3DeriveKeyMaterial, true
+	default:
+		return blake3IV, 0, true
+	}
+}
+
+// hashLanes hashes up to eight inputs of the same length of at most one chunk, writing their digests of the given
+// size one after the other to dst.
+func hashLanes(dst []byte, inputs [][]byte, key *[8]uint32, flags uint32, size int) {
+	var cv [8][blake3Lanes]uint32
+	var block, out [16][blake3Lanes]uint32
+	for i := range cv {
+		for l := range cv[i] {
+			cv[i][l] = key[i]
+		}
+	}
+
+	n := len(inputs[0])
+	blocks := (n + blake3BlockLen - 1) / blake3BlockLen
+	if blocks == 0 {
+		blocks = 1
+	}
+	for b := 0; b < blocks; b++ {
+		start := b * blake3BlockLen
+		blockLen := n - start
+		if blockLen > blake3BlockLen {
+			blockLen = blake3BlockLen
+		}
+		blockFlags := flags
+		if b == 0 {
+			blockFlags |= blake3ChunkStart
+		}
+		if b == blocks-1 {
+			blockFlags |= blake3ChunkEnd | blake3Root
+		}
+
+		for l, input := range inputs {
+			// The last block is padded with zeros.
+			var buf [blake3BlockLen]byte
+			copy(buf[:], input[start:start+blockLen])
+			for i := range block {
+				block[i][l] = binary.LittleEndian.Uint32(buf[4*i:])
+			}
+		}
+		compress8SIMD(&cv, &block, uint32(blockLen), blockFlags, &out)
+		copy(cv[:], out[:8])
+	}
+
+	for l := range inputs {
+		var digest [blake3BlockLen]byte
+		for i := range out {
+			binary.LittleEndian.PutUint32(digest[4*i:], out[i][l])
+		}
+		copy(dst[l*size:(l+1)*size], digest[:size])
+	}
+}
@@ -0,0 +1,20 @@
+//go:build amd64 && !purego
+
+package hash
+
+import "golang.org/x/sys/cpu"
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// useSIMD tells whether the CPU supports AVX2, which compress8SIMD runs on.
+var useSIMD = cpu.X86.HasAVX2
+
+// compress8SIMD compresses a block of each of eight inputs at once, with a zero counter, the eight lanes being the
+// eight words of AVX2 registers. The chaining values, blocks and outputs are transposed: cv[i][l] is word i of the
+// chaining value of lane l, and the output holds the 16 words of the compression function of every lane.
+//
+//go:noescape
+func compress8SIMD(cv *[8][8]uint32, block *[16][8]uint32, blockLen uint32, flags uint32, out *[16][8]uint32)
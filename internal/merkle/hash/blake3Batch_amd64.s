@@ -0,0 +1,147 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// The initialization vector of BLAKE3, the first four words of which start the third row of the state.
+DATA iv<>+0(SB)/4, $0x6a09e667
+DATA iv<>+4(SB)/4, $0xbb67ae85
+DATA iv<>+8(SB)/4, $0x3c6ef372
+DATA iv<>+12(SB)/4, $0xa54ff53a
+GLOBL iv<>(SB), RODATA|NOPTR, $16
+
+// Byte shuffles rotating every 32-bit word right by 16 and by 8 bits.
+DATA rot16<>+0(SB)/8, $0x0504070601000302
+DATA rot16<>+8(SB)/8, $0x0d0c0f0e09080b0a
+DATA rot16<>+16(SB)/8, $0x0504070601000302
+DATA rot16<>+24(SB)/8, $0x0d0c0f0e09080b0a
+GLOBL rot16<>(SB), RODATA|NOPTR, $32
+
+DATA rot8<>+0(SB)/8, $0x0407060500030201
+DATA rot8<>+8(SB)/8, $0x0c0f0e0d080b0a09
+DATA rot8<>+16(SB)/8, $0x0407060500030201
+DATA rot8<>+24(SB)/8, $0x0c0f0e0d080b0a09
+GLOBL rot8<>(SB), RODATA|NOPTR, $32
+
+// HALF_ROUND applies the G function to four columns or diagonals of the states of the eight lanes, the message words
+// being read at the given offsets of the transposed block in SI. The rotations of b need a scratch register, so a0
+// is spilled to the stack while they are computed.
+#define HALF_ROUND(a0, a1, a2, a3, b0, b1, b2, b3, c0, c1, c2, c3, d0, d1, d2, d3, x0, x1, x2, x3, y0, y1, y2, y3) \
+	VPADDD b0, a0, a0; VPADDD b1, a1, a1; VPADDD b2, a2, a2; VPADDD b3, a3, a3; \
+	VPADDD x0(SI), a0, a0; VPADDD x1(SI), a1, a1; VPADDD x2(SI), a2, a2; VPADDD x3(SI), a3, a3; \
+	VPXOR a0, d0, d0; VPXOR a1, d1, d1; VPXOR a2, d2, d2; VPXOR a3, d3, d3; \
+	VPSHUFB rot16<>(SB), d0, d0; VPSHUFB rot16<>(SB), d1, d1; VPSHUFB rot16<>(SB), d2, d2; VPSHUFB rot16<>(SB), d3, d3; \
+	VPADDD d0, c0, c0; VPADDD d1, c1, c1; VPADDD d2, c2, c2; VPADDD d3, c3, c3; \
+	VPXOR c0, b0, b0; VPXOR c1, b1, b1; VPXOR c2, b2, b2; VPXOR c3, b3, b3; \
+	VMOVDQU a0, 0(SP); \
+	VPSRLD $12, b0, a0; VPSLLD $20, b0, b0; VPOR a0, b0, b0; \
+	VPSRLD $12, b1, a0; VPSLLD $20, b1, b1; VPOR a0, b1, b1; \
+	VPSRLD $12, b2, a0; VPSLLD $20, b2, b2; VPOR a0, b2, b2; \
+	VPSRLD $12, b3, a0; VPSLLD $20, b3, b3; VPOR a0, b3, b3; \
+	VMOVDQU 0(SP), a0; \
+	VPADDD b0, a0, a0; VPADDD b1, a1, a1; VPADDD b2, a2, a2; VPADDD b3, a3, a3; \
+	VPADDD y0(SI), a0, a0; VPADDD y1(SI), a1, a1; VPADDD y2(SI), a2, a2; VPADDD y3(SI), a3, a3; \
+	VPXOR a0, d0, d0; VPXOR a1, d1, d1; VPXOR a2, d2, d2; VPXOR a3, d3, d3; \
+	VPSHUFB rot8<>(SB), d0, d0; VPSHUFB rot8<>(SB), d1, d1; VPSHUFB rot8<>(SB), d2, d2; VPSHUFB rot8<>(SB), d3, d3; \
+	VPADDD d0, c0, c0; VPADDD d1, c1, c1; VPADDD d2, c2, c2; VPADDD d3, c3, c3; \
+	VPXOR c0, b0, b0; VPXOR c1, b1, b1; VPXOR c2, b2, b2; VPXOR c3, b3, b3; \
+	VMOVDQU a0, 0(SP); \
+	VPSRLD $7, b0, a0; VPSLLD $25, b0, b0; VPOR a0, b0, b0; \
+	VPSRLD $7, b1, a0; VPSLLD $25, b1, b1; VPOR a0, b1, b1; \
+	VPSRLD $7, b2, a0; VPSLLD $25, b2, b2; VPOR a0, b2, b2; \
+	VPSRLD $7, b3, a0; VPSLLD $25, b3, b3; VPOR a0, b3, b3; \
+	VMOVDQU 0(SP), a0
+
+// func compress8SIMD(cv *[8][8]uint32, block *[16][8]uint32, blockLen uint32, flags uint32, out *[16][8]uint32)
+TEXT ·compress8SIMD(SB), NOSPLIT, $32-32
+	MOVQ cv+0(FP), DI
+	MOVQ block+8(FP), SI
+	MOVQ out+24(FP), DX
+
+	// The state of lane l is made of word l of Y0 to Y15.
+	VMOVDQU 0(DI), Y0
+	VMOVDQU 32(DI), Y1
+	VMOVDQU 64(DI), Y2
+	VMOVDQU 96(DI), Y3
+	VMOVDQU 128(DI), Y4
+	VMOVDQU 160(DI), Y5
+	VMOVDQU 192(DI), Y6
+	VMOVDQU 224(DI), Y7
+	VPBROADCASTD iv<>+0(SB), Y8
+	VPBROADCASTD iv<>+4(SB), Y9
+	VPBROADCASTD iv<>+8(SB), Y10
+	VPBROADCASTD iv<>+12(SB), Y11
+	// The counter is 0, as the inputs are single chunks.
+	VPXOR Y12, Y12, Y12
+	VPXOR Y13, Y13, Y13
+	MOVL blockLen+16(FP), AX
+	VMOVD AX, X14
+	VPBROADCASTD X14, Y14
+	MOVL flags+20(FP), AX
+	VMOVD AX, X15
+	VPBROADCASTD X15, Y15
+
+	// Seven rounds, every one reading the message words in the order of the message permutation applied to the
+	// previous one.
+	// Round 1
+	HALF_ROUND(Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7, Y8, Y9, Y10, Y11, Y12, Y13, Y14, Y15, 0, 64, 128, 192, 32, 96, 160, 224)
+	HALF_ROUND(Y0, Y1, Y2, Y3, Y5, Y6, Y7, Y4, Y10, Y11, Y8, Y9, Y15, Y12, Y13, Y14, 256, 320, 384, 448, 288, 352, 416, 480)
+	// Round 2
+	HALF_ROUND(Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7, Y8, Y9, Y10, Y11, Y12, Y13, Y14, Y15, 64, 96, 224, 128, 192, 320, 0, 416)
+	HALF_ROUND(Y0, Y1, Y2, Y3, Y5, Y6, Y7, Y4, Y10, Y11, Y8, Y9, Y15, Y12, Y13, Y14, 32, 384, 288, 480, 352, 160, 448, 256)
+	// Round 3
+	HALF_ROUND(Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7, Y8, Y9, Y10, Y11, Y12, Y13, Y14, Y15, 96, 320, 416, 224, 128, 384, 64, 448)
+	HALF_ROUND(Y0, Y1, Y2, Y3, Y5, Y6, Y7, Y4, Y10, Y11, Y8, Y9, Y15, Y12, Y13, Y14, 192, 288, 352, 256, 160, 0, 480, 32)
+	// Round 4
+	HALF_ROUND(Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7, Y8, Y9, Y10, Y11, Y12, Y13, Y14, Y15, 320, 384, 448, 416, 224, 288, 96, 480)
+	HALF_ROUND(Y0, Y1, Y2, Y3, Y5, Y6, Y7, Y4, Y10, Y11, Y8, Y9, Y15, Y12, Y13, Y14, 128, 352, 160, 32, 0, 64, 256, 192)
+	// Round 5
+	HALF_ROUND(Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7, Y8, Y9, Y10, Y11, Y12, Y13, Y14, Y15, 384, 288, 480, 448, 416, 352, 320, 256)
+	HALF_ROUND(Y0, Y1, Y2, Y3, Y5, Y6, Y7, Y4, Y10, Y11, Y8, Y9, Y15, Y12, Y13, Y14, 224, 160, 0, 192, 64, 96, 32, 128)
+	// Round 6
+	HALF_ROUND(Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7, Y8, Y9, Y10, Y11, Y12, Y13, Y14, Y15, 288, 352, 256, 480, 448, 160, 384, 32)
+	HALF_ROUND(Y0, Y1, Y2, Y3, Y5, Y6, Y7, Y4, Y10, Y11, Y8, Y9, Y15, Y12, Y13, Y14, 416, 0, 64, 128, 96, 320, 192, 224)
+	// Round 7
+	HALF_ROUND(Y0, Y1, Y2, Y3, Y4, Y5, Y6, Y7, Y8, Y9, Y10, Y11, Y12, Y13, Y14, Y15, 352, 160, 32, 256, 480, 0, 288, 192)
+	HALF_ROUND(Y0, Y1, Y2, Y3, Y5, Y6, Y7, Y4, Y10, Y11, Y8, Y9, Y15, Y12, Y13, Y14, 448, 64, 96, 224, 320, 384, 128, 416)
+
+	// The first half of the output is the new chaining value, the second half extends the root output to 64 bytes.
+	VPXOR Y8, Y0, Y0
+	VPXOR Y9, Y1, Y1
+	VPXOR Y10, Y2, Y2
+	VPXOR Y11, Y3, Y3
+	VPXOR Y12, Y4, Y4
+	VPXOR Y13, Y5, Y5
+	VPXOR Y14, Y6, Y6
+	VPXOR Y15, Y7, Y7
+	VPXOR 0(DI), Y8, Y8
+	VPXOR 32(DI), Y9, Y9
+	VPXOR 64(DI), Y10, Y10
+	VPXOR 96(DI), Y11, Y11
+	VPXOR 128(DI), Y12, Y12
+	VPXOR 160(DI), Y13, Y13
+	VPXOR 192(DI), Y14, Y14
+	VPXOR 224(DI), Y15, Y15
+	VMOVDQU Y0, 0(DX)
+	VMOVDQU Y1, 32(DX)
+	VMOVDQU Y2, 64(DX)
+	VMOVDQU Y3, 96(DX)
+	VMOVDQU Y4, 128(DX)
+	VMOVDQU Y5, 160(DX)
+	VMOVDQU Y6, 192(DX)
+	VMOVDQU Y7, 224(DX)
+	VMOVDQU Y8, 256(DX)
+	VMOVDQU Y9, 288(DX)
+	VMOVDQU Y10, 320(DX)
+	VMOVDQU Y11, 352(DX)
+	VMOVDQU Y12, 384(DX)
+	VMOVDQU Y13, 416(DX)
+	VMOVDQU Y14, 448(DX)
+	VMOVDQU Y15, 480(DX)
+
+	VZEROUPPER
+	RET
@@ -0,0 +1,17 @@
+//go:build !amd64 || purego
+
+package hash
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// useSIMD is never set without a SIMD implementation of compress8SIMD, so that BLAKE3 batches are hashed one input
+// at a time.
+var useSIMD = false
+
+// compress8SIMD is not available on this platform.
+func compress8SIMD(cv *[8][8]uint32, block *[16][8]uint32, blockLen uint32, flags uint32, out *[16][8]uint32) {
+	panic("hash: no SIMD implementation of the BLAKE3 compression")
+}
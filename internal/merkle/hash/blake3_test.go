@@ -3,13 +3,22 @@ package hash_test
 import (
 	"encoding/hex"
 	"fmt"
-	merkletree "github.com/reactivejson/merkleTree/internal/merkle"
 	hash2 "github.com/reactivejson/merkleTree/internal/merkle/hash"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// stringToByte  turn a string in to a byte array
+func stringToByte(input string) []byte {
+	x, err := hex.DecodeString(strings.TrimPrefix(input, "0x"))
+	if err != nil {
+		panic(err)
+	}
+	return x
+}
+
 func TestBlake3(t *testing.T) {
 	tests := []struct {
 		input []byte
@@ -17,7 +26,7 @@ func TestBlake3(t *testing.T) {
 	}{
 		{
 			input: []byte("Consensys"),
-			hash:  merkletree.stringToByte("37d279155d7afba864451532eb236103d43b8d410806322ea36be2b8f7731dfd"),
+			hash:  stringToByte("37d279155d7afba864451532eb236103d43b8d410806322ea36be2b8f7731dfd"),
 		},
 	}
 
@@ -51,3 +60,204 @@ func TestConcatHash(t *testing.T) {
 		assert.Equal(t, test.hash, res, fmt.Sprintf("failed at test %d", i))
 	}
 }
+
+// blake3Input is the input of length n of the official BLAKE3 test vectors.
+func blake3Input(n int) []byte {
+	input := make([]byte, n)
+	for i := range input {
+		input[i] = byte(i % 251)
+	}
+	return input
+}
+
+func TestBlake3Modes(t *testing.T) {
+	// Official BLAKE3 test vectors.
+	var key [32]byte
+	copy(key[:], "whats the Elvish word for friend")
+	context := "BLAKE3 2019-12-27 16:29:52 test vectors context"
+
+	tests := []struct {
+		length    int
+		hash      []byte
+		keyed     []byte
+		deriveKey []byte
+	}{
+		{ // 0
+			length:    0,
+			hash:      stringToByte("af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262"),
+			keyed:     stringToByte("92b2b75604ed3c761f9d6f62392c8a9227ad0ea3f09573e783f1498a4ed60d26"),
+			deriveKey: stringToByte("2cc39783c223154fea8dfb7c1b1660f2ac2dcbd1c1de8277b0b0dd39b7e50d7d"),
+		},
+		{ // 1
+			length:    1,
+			hash:      stringToByte("2d3adedff11b61f14c886e35afa036736dcd87a74d27b5c1510225d0f592e213"),
+			keyed:     stringToByte("6d7878dfff2f485635d39013278ae14f1454b8c0a3a2d34bc1ab38228a80c95b"),
+			deriveKey: stringToByte("b3e2e340a117a499c6cf2398a19ee0d29cca2bb7404c73063382693bf66cb06c"),
+		},
+	}
+
+	for i, test := range tests {
+		input := blake3Input(test.length)
+		assert.Equal(t, test.hash, hash2.NewBlake3().Hash(input), fmt.Sprintf("failed at test %d", i))
+		assert.Equal(t, test.keyed, hash2.NewBlake3Keyed(key).Hash(input), fmt.Sprintf("failed at test %d", i))
+		assert.Equal(t, test.deriveKey, hash2.NewBlake3DeriveKey(context).Hash(input), fmt.Sprintf("failed at test %d", i))
+	}
+}
+
+func TestBlake3ModesStreaming(t *testing.T) {
+	var key [32]byte
+	copy(key[:], "whats the Elvish word for friend")
+	modes := []*hash2.BLAKE3{
+		hash2.NewBlake3(),
+		hash2.NewBlake3Keyed(key),
+		hash2.NewBlake3DeriveKey("merkle tree test"),
+	}
+
+	input := [][]byte{[]byte("Merle-tree"), []byte("Blake3"), []byte("Consensys")}
+	for i, mode := range modes {
+		h := mode.NewHasher()
+		for _, d := range input {
+			h.Write(d)
+		}
+		assert.Equal(t, mode.Hash(input...), h.Sum(nil), fmt.Sprintf("failed at test %d", i))
+	}
+
+	// The modes never agree with one another.
+	assert.NotEqual(t, modes[0].Hash(input...), modes[1].Hash(input...))
+	assert.NotEqual(t, modes[0].Hash(input...), modes[2].Hash(input...))
+	assert.NotEqual(t, modes[1].Hash(input...), modes[2].Hash(input...))
+	assert.Equal(t, "blake3-keyed", modes[1].Name())
+	assert.Equal(t, "blake3-derive-key", modes[2].Name())
+}
+
+func TestBlake3KeyedReuse(t *testing.T) {
+	var key [32]byte
+	copy(key[:], "whats the Elvish word for friend")
+	keyed := hash2.NewBlake3Keyed(key)
+	short, err := keyed.WithLength(16)
+	assert.NoError(t, err)
+
+	// The hashers of the keyed mode are reused, and reset between hashes.
+	want := keyed.Hash(blake3Input(1))
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, want, keyed.Hash(blake3Input(1)), fmt.Sprintf("failed at test %d", i))
+		assert.Equal(t, want[:16], short.Hash(blake3Input(1)), fmt.Sprintf("failed at test %d", i))
+		keyed.Hash(blake3Input(2000))
+	}
+
+	// Only the digest is allocated, rather than a hasher holding the key.
+	input := []byte("Consensys")
+	allocs := testing.AllocsPerRun(100, func() { keyed.Hash(input) })
+	assert.LessOrEqual(t, allocs, float64(1))
+}
+
+func TestBlake3WithLength(t *testing.T) {
+	input := []byte("Consensys")
+	full := hash2.NewBlake3().Hash(input)
+	var key [32]byte
+	copy(key[:], "whats the Elvish word for friend")
+
+	for i, length := range []int{16, 20, 32, 64} {
+		h, err := hash2.NewBlake3WithLength(length)
+		assert.NoError(t, err)
+		assert.Equal(t, length, h.HashLength(), fmt.Sprintf("failed at test %d", i))
+		res := h.Hash(input)
+		assert.Len(t, res, length, fmt.Sprintf("failed at test %d", i))
+
+		// The output is extended, not recomputed: the default digest is a prefix of longer ones and the other way around.
+		n := length
+		if n > len(full) {
+			n = len(full)
+		}
+		assert.Equal(t, full[:n], res[:n], fmt.Sprintf("failed at test %d", i))
+
+		stream := h.NewHasher()
+		stream.Write(input)
+		assert.Equal(t, res, stream.Sum(nil), fmt.Sprintf("failed at test %d", i))
+
+		// The length applies to the other modes as well.
+		keyed, err := hash2.WithLength(hash2.NewBlake3Keyed(key), length)
+		assert.NoError(t, err)
+		assert.Len(t, keyed.Hash(input), length, fmt.Sprintf("failed at test %d", i))
+		assert.Equal(t, keyed.Hash(input), sumStream(keyed.(hash2.Streamer), input), fmt.Sprintf("failed at test %d", i))
+		derived, err := hash2.WithLength(hash2.NewBlake3DeriveKey("merkle tree test"), length)
+		assert.NoError(t, err)
+		assert.Len(t, derived.Hash(input), length, fmt.Sprintf("failed at test %d", i))
+	}
+
+	for _, length := range []int{0, 8, 15, 65} {
+		_, err := hash2.NewBlake3WithLength(length)
+		assert.EqualError(t, err, "the length of blake3 hashes must be between 16 and 64 bytes")
+	}
+}
+
+func TestWithLength(t *testing.T) {
+	h, err := hash2.WithLength(hash2.NewSHA256(), 32)
+	assert.NoError(t, err)
+	assert.Equal(t, 32, h.HashLength())
+
+	_, err = hash2.WithLength(hash2.NewSHA256(), 20)
+	assert.EqualError(t, err, "the hash length is fixed to 32 bytes")
+}
+
+// sumStream hashes the input through a hasher of the hash type.
+func sumStream(h hash2.Streamer, data []byte) []byte {
+	stream := h.NewHasher()
+	stream.Write(data)
+	return stream.Sum(nil)
+}
+
+func TestBlake3Batch(t *testing.T) {
+	var key [32]byte
+	copy(key[:], "whats the Elvish word for friend")
+	blake3x16, _ := hash2.NewBlake3WithLength(16)
+	blake3x64, _ := hash2.NewBlake3WithLength(64)
+	modes := []*hash2.BLAKE3{
+		hash2.NewBlake3(),
+		hash2.NewBlake3Keyed(key),
+		hash2.NewBlake3DeriveKey("merkle tree test"),
+		blake3x16,
+		blake3x64,
+	}
+
+	check := func(kernel string) {
+		for i, mode := range modes {
+			for _, length := range []int{0, 1, 63, 64, 65, 200, 1024, 1025} {
+				for _, count := range []int{1, 7, 8, 9, 20} {
+					inputs := make([][]byte, count)
+					want := make([]byte, 0, count*mode.HashLength())
+					for j := range inputs {
+						inputs[j] = blake3Input(length + j)[j:]
+						want = append(want, mode.Hash(inputs[j])...)
+					}
+					dst := make([]byte, count*mode.HashLength())
+					mode.HashBatch(dst, inputs)
+					assert.Equal(t, want, dst, fmt.Sprintf("failed with %s kernel at test %d length %d count %d", kernel, i, length, count))
+				}
+			}
+
+			// Inputs of different lengths are hashed one at a time.
+			inputs := [][]byte{[]byte("Merle-tree"), []byte("Blake3"), []byte("Consensys")}
+			dst := make([]byte, len(inputs)*mode.HashLength())
+			mode.HashBatch(dst, inputs)
+			assert.Equal(t, mode.Hash(inputs[0]), dst[:mode.HashLength()], fmt.Sprintf("failed with %s kernel at test %d", kernel, i))
+			assert.Equal(t, mode.Hash(inputs[2]), dst[2*mode.HashLength():], fmt.Sprintf("failed with %s kernel at test %d", kernel, i))
+		}
+	}
+
+	check("SIMD")
+	restore := hash2.DisableSIMD()
+	defer restore()
+	check("scalar")
+}
+
+func TestHashBatch(t *testing.T) {
+	inputs := [][]byte{[]byte("Merle-tree"), []byte("Blake3"), []byte("Consensys")}
+	for i, h := range []hash2.HashType{hash2.NewBlake3(), hash2.NewSHA256(), hash2.NewKeccak256()} {
+		dst := make([]byte, len(inputs)*h.HashLength())
+		hash2.HashBatch(h, dst, inputs)
+		for j, input := range inputs {
+			assert.Equal(t, h.Hash(input), dst[j*h.HashLength():(j+1)*h.HashLength()], fmt.Sprintf("failed at test %d input %d", i, j))
+		}
+	}
+}
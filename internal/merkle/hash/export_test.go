@@ -0,0 +1,8 @@
+package hash
+
+// DisableSIMD makes BLAKE3 batches hash their inputs one at a time, until the returned function is called.
+func DisableSIMD() (restore func()) {
+	saved := useSIMD
+	useSIMD = false
+	return func() { useSIMD = saved }
+}
@@ -1,5 +1,10 @@
 package hash
 
+import (
+	"fmt"
+	gohash "hash"
+)
+
 /**
  * @author Mohamed-Aly Bou-Hanane
  * © 2023
@@ -16,3 +21,78 @@ type HashType interface {
 	// HashLength provides the length of the hash.
 	HashLength() int
 }
+
+// Streamer is implemented by hash types that can hash their input incrementally.
+// The tree uses it to hash nodes without concatenating their children first, and to write the digests into memory
+// allocated once per tree instead of once per node. Hash types that don't implement it are used through Hash.
+type Streamer interface {
+	HashType
+
+	// NewHasher returns a hash.Hash computing the same digest as Hash over everything written to it.
+	NewHasher() gohash.Hash
+}
+
+// BatchHasher is implemented by hash types that can hash several inputs at once, such as multi-buffer SIMD
+// implementations hashing independent inputs in the lanes of vector registers. The tree hashes its leaves and
+// interior nodes in batches when the hash type implements it.
+type BatchHasher interface {
+	HashType
+
+	// HashBatch hashes every input as Hash does, writing their digests one after the other to dst, which must be
+	// len(inputs)*HashLength() bytes long. The inputs are usually of the same length, as tree nodes are; any input
+	// length must be supported, but only inputs of the same length may be hashed in parallel.
+	HashBatch(dst []byte, inputs [][]byte)
+}
+
+// HashBatch hashes every input with h, writing their digests one after the other to dst, which must be
+// len(inputs)*HashLength() bytes long. It uses HashBatch when h is a BatchHasher, and hashes the inputs one at a
+// time otherwise.
+func HashBatch(h HashType, dst []byte, inputs [][]byte) {
+	if batcher, ok := h.(BatchHasher); ok {
+		batcher.HashBatch(dst, inputs)
+		return
+	}
+	size := h.HashLength()
+	for i, input := range inputs {
+		copy(dst[i*size:(i+1)*size], h.Hash(input))
+	}
+}
+
+// Validator is implemented by hash types that can't hash every input, such as Poseidon which hashes field elements.
+// Their Hash panics on inputs Validate rejects: the tree validates its data, and the hashes of the proofs it verifies,
+// before hashing them.
+type Validator interface {
+	HashType
+
+	// Validate returns an error if Hash can't be called with the inputs.
+	Validate(inputs ...[]byte) error
+}
+
+// Validate returns an error if h can't hash the inputs, which only happens when h is a Validator.
+func Validate(h HashType, inputs ...[]byte) error {
+	if validator, ok := h.(Validator); ok {
+		return validator.Validate(inputs...)
+	}
+	return nil
+}
+
+// XOF is implemented by hash types with an extendable output, whose digests can be made shorter or longer.
+type XOF interface {
+	HashType
+
+	// WithLength returns the same hash type with digests of the given length in bytes, or an error if the length
+	// is not supported.
+	WithLength(length int) (HashType, error)
+}
+
+// WithLength returns the hash type with digests of the given length in bytes.
+// Only an XOF can change its length; any other hash type is returned as is if it already has that length.
+func WithLength(h HashType, length int) (HashType, error) {
+	if xof, ok := h.(XOF); ok {
+		return xof.WithLength(length)
+	}
+	if h.HashLength() != length {
+		return nil, fmt.Errorf("the hash length is fixed to %d bytes", h.HashLength())
+	}
+	return h, nil
+}
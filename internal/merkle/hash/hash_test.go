@@ -0,0 +1,34 @@
+package hash_test
+
+import (
+	"fmt"
+	hash2 "github.com/reactivejson/merkleTree/internal/merkle/hash"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamer(t *testing.T) {
+	input := [][]byte{[]byte("Merle-tree"), []byte("Blake3"), []byte("Consensys")}
+	for i, name := range []string{"blake3", "sha256", "sha256d", "sha512-256", "sha3-256", "keccak256"} {
+		hash, err := hash2.ByName(name)
+		assert.NoError(t, err)
+		streamer, ok := hash.(hash2.Streamer)
+		if !assert.True(t, ok, fmt.Sprintf("not a streamer at test %d", i)) {
+			continue
+		}
+
+		h := streamer.NewHasher()
+		assert.Equal(t, hash.HashLength(), h.Size(), fmt.Sprintf("failed at test %d", i))
+		for _, d := range input {
+			h.Write(d)
+		}
+		assert.Equal(t, hash.Hash(input...), h.Sum(nil), fmt.Sprintf("failed at test %d", i))
+
+		// The hasher is reusable after a reset, and appends to the buffer given to Sum.
+		h.Reset()
+		h.Write([]byte("abc"))
+		dst := make([]byte, 0, hash.HashLength())
+		assert.Equal(t, hash.Hash([]byte("abc")), h.Sum(dst), fmt.Sprintf("failed at test %d", i))
+	}
+}
@@ -0,0 +1,272 @@
+package hash
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+const _poseidonhashlength = 32
+
+// poseidonMaxInputs is the largest number of field elements circomlib hashes at once.
+const poseidonMaxInputs = 16
+
+// poseidonInputs are the numbers of field elements Poseidon hashes. The parameters of the other widths of circomlib
+// would need the reference implementation's resampling of the MDS matrices failing its security checks, which isn't
+// reproduced, and there are no test vectors to check them against.
+var poseidonInputs = map[int]bool{1: true, 2: true, 4: true, 5: true}
+
+// poseidonFullRounds is the number of full rounds, half of them before the partial rounds and half after.
+const poseidonFullRounds = 8
+
+// poseidonPartialRounds is the number of partial rounds for each width t = 2..17 of the state.
+var poseidonPartialRounds = [poseidonMaxInputs]int{56, 57, 56, 60, 60, 63, 64, 63, 60, 66, 60, 65, 70, 60, 64, 68}
+
+// bn254 is the order of the scalar field of the BN254 curve, the field of the circuits Poseidon is designed for.
+var bn254, _ = new(big.Int).SetString("21888242871839275222246405745257275088548364400416034343698204186575808495617", 10)
+
+// Poseidon is the Poseidon hashing method over the scalar field of BN254, compatible with circomlib.
+// Poseidon is cheap to compute inside zero-knowledge circuits, so that membership in a tree built with it can be
+// proven in a circuit with the same root as off-chain.
+//
+// Every input byte array is one field element, encoded in big-endian on at most 32 bytes, and the hash is the
+// field element circomlib's Poseidon computes for those inputs, encoded in big-endian on 32 bytes. Poseidon hashes
+// 1, 2, 4 or 5 inputs, and Hash panics on the inputs Validate rejects. With the default options a leaf is the hash
+// of its input alone, and a node the hash of its two children; domain separation would hash nodes of 3 inputs, and
+// is not supported.
+type Poseidon struct{}
+
+// NewPoseidon creates a new Poseidon hashing method.
+func NewPoseidon() *Poseidon {
+	return &Poseidon{}
+}
+
+// Name returns the identifier of Poseidon in the registry.
+func (h *Poseidon) Name() string {
+	return "poseidon"
+}
+
+// HashLength returns the length of hashes generated by Hash() in bytes.
+func (h *Poseidon) HashLength() int {
+	return _poseidonhashlength
+}
+
+// Validate returns an error if the inputs are not 1, 2, 4 or 5 field elements.
+func (h *Poseidon) Validate(data ...[]byte) error {
+	if !poseidonInputs[len(data)] {
+		return errors.New("poseidon hashes 1, 2, 4 or 5 field elements")
+	}
+	for _, d := range data {
+		if len(d) > _poseidonhashlength || new(big.Int).SetBytes(d).Cmp(bn254) >= 0 {
+			return errors.New("the poseidon input is not a field element")
+		}
+	}
+	return nil
+}
+
+// Hash generates a Poseidon hash from input field elements.
+func (h *Poseidon) Hash(data ...[]byte) []byte {
+	if err := h.Validate(data...); err != nil {
+		panic("hash: " + err.Error())
+	}
+	inputs := make([]*big.Int, len(data))
+	for i, d := range data {
+		inputs[i] = new(big.Int).SetBytes(d)
+	}
+
+	hash := make([]byte, _poseidonhashlength)
+	return poseidon(inputs).FillBytes(hash)
+}
+
+// poseidon computes the Poseidon permutation on a state made of a zero capacity element followed by the inputs,
+// and returns the first element of the state.
+func poseidon(inputs []*big.Int) *big.Int {
+	t := len(inputs) + 1
+	params := poseidonParamsFor(t)
+	partialRounds := poseidonPartialRounds[t-2]
+
+	state := make([]*big.Int, t)
+	state[0] = new(big.Int)
+	for i, input := range inputs {
+		state[i+1] = new(big.Int).Set(input)
+	}
+
+	mixed := make([]*big.Int, t)
+	for i := range mixed {
+		mixed[i] = new(big.Int)
+	}
+	product := new(big.Int)
+	for r := 0; r < poseidonFullRounds+partialRounds; r++ {
+		// Add the round constants.
+		for i := range state {
+			state[i].Add(state[i], params.c[r*t+i])
+		}
+		// Apply the S-box x^5, to the whole state in full rounds and to its first element in partial rounds.
+		if r < poseidonFullRounds/2 || r >= poseidonFullRounds/2+partialRounds {
+			for i := range state {
+				pow5(state[i])
+			}
+		} else {
+			pow5(state[0])
+		}
+		// Multiply by the MDS matrix.
+		for i := range mixed {
+			mixed[i].SetInt64(0)
+			for j := range state {
+				mixed[i].Add(mixed[i], product.Mul(params.m[i][j], state[j]))
+			}
+			mixed[i].Mod(mixed[i], bn254)
+		}
+		state, mixed = mixed, state
+	}
+	return state[0]
+}
+
+// pow5 sets x to x^5 in the field.
+func pow5(x *big.Int) {
+	x2 := new(big.Int).Mul(x, x)
+	x2.Mod(x2, bn254)
+	x4 := x2.Mul(x2, x2)
+	x4.Mod(x4, bn254)
+	x.Mul(x, x4)
+	x.Mod(x, bn254)
+}
+
+// poseidonParams holds the round constants, round by round, and the MDS matrix for one width of the state.
+type poseidonParams struct {
+	c []*big.Int
+	m [][]*big.Int
+}
+
+var (
+	poseidonOnce   [poseidonMaxInputs]sync.Once
+	poseidonTables [poseidonMaxInputs]poseidonParams
+)
+
+// poseidonParamsFor returns the parameters for a state of width t, generating them on first use.
+func poseidonParamsFor(t int) poseidonParams {
+	poseidonOnce[t-2].Do(func() {
+		poseidonTables[t-2] = newPoseidonParams(t, poseidonPartialRounds[t-2])
+	})
+	return poseidonTables[t-2]
+}
+
+// newPoseidonParams generates the round constants and the MDS matrix of width t the way the reference
+// implementation of Poseidon does, which is how the constants of circomlib were generated: both are drawn
+// from a Grain LFSR seeded with the parameters of the permutation.
+func newPoseidonParams(t, partialRounds int) poseidonParams {
+	const fieldSize = 254
+	grain := newGrainLFSR(fieldSize, t, poseidonFullRounds, partialRounds)
+
+	var params poseidonParams
+	// The round constants are sampled by rejection, so that they are uniform in the field.
+	params.c = make([]*big.Int, (poseidonFullRounds+partialRounds)*t)
+	for i := range params.c {
+		c := grain.field(fieldSize)
+		for c.Cmp(bn254) >= 0 {
+			c = grain.field(fieldSize)
+		}
+		params.c[i] = c
+	}
+
+	// The MDS matrix is the Cauchy matrix 1 / (x_i + y_j) of 2t distinct elements, reduced into the field.
+	// The reference implementation also draws a new matrix when one fails its invariant subspace checks, which is
+	// not reproduced here: the constants are checked against the test vectors of circomlib instead.
+	for {
+		xy := make([]*big.Int, 2*t)
+		for distinct := false; !distinct; {
+			for i := range xy {
+				xy[i] = grain.field(fieldSize)
+				xy[i].Mod(xy[i], bn254)
+			}
+			distinct = true
+			for i := range xy {
+				for j := i + 1; j < len(xy); j++ {
+					if xy[i].Cmp(xy[j]) == 0 {
+						distinct = false
+					}
+				}
+			}
+		}
+
+		params.m = make([][]*big.Int, t)
+		invertible := true
+		for i := 0; i < t && invertible; i++ {
+			params.m[i] = make([]*big.Int, t)
+			for j := 0; j < t; j++ {
+				sum := new(big.Int).Add(xy[i], xy[t+j])
+				sum.Mod(sum, bn254)
+				if sum.Sign() == 0 {
+					invertible = false
+					break
+				}
+				params.m[i][j] = sum.ModInverse(sum, bn254)
+			}
+		}
+		if invertible {
+			return params
+		}
+	}
+}
+
+// grainLFSR is the 80-bit Grain LFSR the reference implementation of Poseidon draws its parameters from.
+type grainLFSR struct {
+	state [80]byte
+}
+
+// newGrainLFSR seeds the LFSR with the parameters of a Poseidon permutation over a prime field with the x^5 S-box,
+// and discards its first 160 bits.
+func newGrainLFSR(fieldSize, t, fullRounds, partialRounds int) *grainLFSR {
+	g := &grainLFSR{}
+	bits := g.state[:0]
+	appendBits := func(value, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, byte(value>>uint(i))&1)
+		}
+	}
+	appendBits(1, 2) // prime field
+	appendBits(0, 4) // x^alpha S-box
+	appendBits(fieldSize, 12)
+	appendBits(t, 12)
+	appendBits(fullRounds, 10)
+	appendBits(partialRounds, 10)
+	appendBits(1<<30-1, 30)
+
+	for i := 0; i < 160; i++ {
+		g.next()
+	}
+	return g
+}
+
+// next shifts the LFSR and returns the new bit.
+func (g *grainLFSR) next() byte {
+	bit := g.state[62] ^ g.state[51] ^ g.state[38] ^ g.state[23] ^ g.state[13] ^ g.state[0]
+	copy(g.state[:], g.state[1:])
+	g.state[79] = bit
+	return bit
+}
+
+// bit returns the next output bit: bits are drawn in pairs, and the second one is output when the first one is set.
+func (g *grainLFSR) bit() byte {
+	for {
+		if g.next() == 1 {
+			return g.next()
+		}
+		g.next()
+	}
+}
+
+// field returns the integer made of the next n output bits, most significant first.
+func (g *grainLFSR) field(n int) *big.Int {
+	x := new(big.Int)
+	for i := 0; i < n; i++ {
+		x.Lsh(x, 1)
+		x.SetBit(x, 0, uint(g.bit()))
+	}
+	return x
+}
@@ -0,0 +1,109 @@
+package hash_test
+
+import (
+	"fmt"
+	hash2 "github.com/reactivejson/merkleTree/internal/merkle/hash"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fieldElement encodes a decimal number as a 32-byte big-endian field element.
+func fieldElement(decimal string) []byte {
+	x, ok := new(big.Int).SetString(decimal, 10)
+	if !ok {
+		panic(decimal)
+	}
+	return x.FillBytes(make([]byte, 32))
+}
+
+func TestPoseidon(t *testing.T) {
+	// Test vectors of circomlib.
+	tests := []struct {
+		input [][]byte
+		hash  []byte
+	}{
+		{ // 0
+			input: [][]byte{fieldElement("1")},
+			hash:  fieldElement("18586133768512220936620570745912940619677854269274689475585506675881198879027"),
+		},
+		{ // 1
+			input: [][]byte{fieldElement("1"), fieldElement("2")},
+			hash:  fieldElement("7853200120776062878684798364095072458815029376092732009249414926327459813530"),
+		},
+		{ // 2
+			input: [][]byte{fieldElement("1"), fieldElement("2"), fieldElement("3"), fieldElement("4")},
+			hash:  fieldElement("18821383157269793795438455681495246036402687001665670618754263018637548127333"),
+		},
+		{ // 3
+			input: [][]byte{fieldElement("1"), fieldElement("2"), fieldElement("0"), fieldElement("0"), fieldElement("0")},
+			hash:  fieldElement("1018317224307729531995786483840663576608797660851238720571059489595066344487"),
+		},
+		{ // 4: inputs don't have to be padded to 32 bytes.
+			input: [][]byte{{0x01}, {0x02}},
+			hash:  fieldElement("7853200120776062878684798364095072458815029376092732009249414926327459813530"),
+		},
+	}
+
+	hash := hash2.NewPoseidon()
+	assert.Equal(t, 32, hash.HashLength())
+	for i, test := range tests {
+		assert.Equal(t, test.hash, hash.Hash(test.input...), fmt.Sprintf("failed at test %d", i))
+	}
+}
+
+func TestPoseidonInvalidInput(t *testing.T) {
+	hash := hash2.NewPoseidon()
+	assert.Panics(t, func() { hash.Hash() })
+	// Only the widths checked against test vectors are supported.
+	assert.Panics(t, func() { hash.Hash(make([][]byte, 3)...) })
+	assert.Panics(t, func() { hash.Hash(make([][]byte, 6)...) })
+	assert.Panics(t, func() { hash.Hash(make([][]byte, 17)...) })
+	assert.Panics(t, func() { hash.Hash(make([]byte, 33)) })
+	// The order of the field is not a field element.
+	assert.Panics(t, func() {
+		hash.Hash(fieldElement("21888242871839275222246405745257275088548364400416034343698204186575808495617"))
+	})
+	assert.NotPanics(t, func() {
+		hash.Hash(fieldElement("21888242871839275222246405745257275088548364400416034343698204186575808495616"))
+	})
+}
+
+func TestPoseidonValidate(t *testing.T) {
+	tests := []struct {
+		input [][]byte
+		err   string
+	}{
+		{ // 0
+			input: [][]byte{fieldElement("1"), fieldElement("2")},
+		},
+		{ // 1
+			input: nil,
+			err:   "poseidon hashes 1, 2, 4 or 5 field elements",
+		},
+		{ // 2
+			input: make([][]byte, 3),
+			err:   "poseidon hashes 1, 2, 4 or 5 field elements",
+		},
+		{ // 3
+			input: [][]byte{[]byte("a leaf longer than thirty-two bytes")},
+			err:   "the poseidon input is not a field element",
+		},
+		{ // 4
+			input: [][]byte{fieldElement("21888242871839275222246405745257275088548364400416034343698204186575808495617")},
+			err:   "the poseidon input is not a field element",
+		},
+	}
+
+	hash := hash2.NewPoseidon()
+	for i, test := range tests {
+		err := hash2.Validate(hash, test.input...)
+		if test.err == "" {
+			assert.NoError(t, err, fmt.Sprintf("unexpected error at test %d", i))
+		} else {
+			assert.EqualError(t, err, test.err, fmt.Sprintf("unexpected error at test %d", i))
+		}
+	}
+	assert.NoError(t, hash2.Validate(hash2.NewSHA256(), make([][]byte, 3)...))
+}
@@ -0,0 +1,78 @@
+package hash
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// Named is implemented by hash types that have a stable identifier, used to refer to them in the registry,
+// the API and serialized proofs.
+type Named interface {
+	// Name returns the identifier of the hash type.
+	Name() string
+}
+
+var (
+	registryMu sync.RWMutex
+	// registry maps the identifier of each hash type to a function creating it.
+	registry = map[string]func() HashType{
+		"blake3":     func() HashType { return NewBlake3() },
+		"sha256":     func() HashType { return NewSHA256() },
+		"sha256d":    func() HashType { return NewDoubleSHA256() },
+		"sha512-256": func() HashType { return NewSHA512t256() },
+		"sha3-256":   func() HashType { return NewSHA3() },
+		"keccak256":  func() HashType { return NewKeccak256() },
+		"poseidon":   func() HashType { return NewPoseidon() },
+	}
+)
+
+// Register makes a hash type available by name.
+// If Register is called twice with the same name or if newHash is nil, it panics.
+func Register(name string, newHash func() HashType) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if newHash == nil {
+		panic("hash: Register hash type is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic("hash: Register called twice for hash type " + name)
+	}
+	registry[name] = newHash
+}
+
+// ByName creates the hash type registered under the name.
+func ByName(name string) (HashType, error) {
+	registryMu.RLock()
+	newHash, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown hash type %q", name)
+	}
+	return newHash(), nil
+}
+
+// Names returns the sorted names of the registered hash types.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NameOf returns the identifier of a hash type, or an empty string if it has none.
+func NameOf(h HashType) string {
+	if named, ok := h.(Named); ok {
+		return named.Name()
+	}
+	return ""
+}
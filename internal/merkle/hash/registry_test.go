@@ -0,0 +1,38 @@
+package hash_test
+
+import (
+	hash2 "github.com/reactivejson/merkleTree/internal/merkle/hash"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestByName(t *testing.T) {
+	for _, name := range []string{"blake3", "sha256", "sha256d", "sha512-256", "sha3-256", "keccak256", "poseidon"} {
+		hash, err := hash2.ByName(name)
+		assert.NoError(t, err)
+		assert.Equal(t, name, hash2.NameOf(hash))
+	}
+
+	_, err := hash2.ByName("md5")
+	assert.EqualError(t, err, `unknown hash type "md5"`)
+}
+
+// customHash is a hash type without a name.
+type customHash struct{ hash2.SHA256 }
+
+func TestRegister(t *testing.T) {
+	hash2.Register("custom-sha256", func() hash2.HashType { return &customHash{} })
+	assert.Contains(t, hash2.Names(), "custom-sha256")
+
+	hash, err := hash2.ByName("custom-sha256")
+	assert.NoError(t, err)
+	assert.Equal(t, hash2.NewSHA256().Hash([]byte("abc")), hash.Hash([]byte("abc")))
+
+	assert.Panics(t, func() {
+		hash2.Register("sha256", func() hash2.HashType { return hash2.NewSHA256() })
+	})
+	assert.Panics(t, func() {
+		hash2.Register("nil", nil)
+	})
+}
@@ -0,0 +1,116 @@
+package hash
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	gohash "hash"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// SHA256 is the SHA-256 hashing method.
+type SHA256 struct{}
+
+// NewSHA256 creates a new SHA-256 hashing method.
+func NewSHA256() *SHA256 {
+	return &SHA256{}
+}
+
+// Name returns the identifier of SHA-256 in the registry.
+func (h *SHA256) Name() string {
+	return "sha256"
+}
+
+// HashLength returns the length of hashes generated by Hash() in bytes.
+func (h *SHA256) HashLength() int {
+	return sha256.Size
+}
+
+// Hash generates a SHA-256 hash from input byte arrays.
+func (h *SHA256) Hash(data ...[]byte) []byte {
+	return sum(h.NewHasher(), data)
+}
+
+// NewHasher returns a hash.Hash computing the SHA-256 hash of the data written to it.
+func (h *SHA256) NewHasher() gohash.Hash {
+	return sha256.New()
+}
+
+// SHA512t256 is the SHA-512/256 hashing method: SHA-512 truncated to 256 bits, with its own initial values.
+type SHA512t256 struct{}
+
+// NewSHA512t256 creates a new SHA-512/256 hashing method.
+func NewSHA512t256() *SHA512t256 {
+	return &SHA512t256{}
+}
+
+// Name returns the identifier of SHA-512/256 in the registry.
+func (h *SHA512t256) Name() string {
+	return "sha512-256"
+}
+
+// HashLength returns the length of hashes generated by Hash() in bytes.
+func (h *SHA512t256) HashLength() int {
+	return sha512.Size256
+}
+
+// Hash generates a SHA-512/256 hash from input byte arrays.
+func (h *SHA512t256) Hash(data ...[]byte) []byte {
+	return sum(h.NewHasher(), data)
+}
+
+// NewHasher returns a hash.Hash computing the SHA-512/256 hash of the data written to it.
+func (h *SHA512t256) NewHasher() gohash.Hash {
+	return sha512.New512_256()
+}
+
+// DoubleSHA256 is the double SHA-256 hashing method of Bitcoin, SHA-256 applied to the SHA-256 hash of the input.
+type DoubleSHA256 struct{}
+
+// NewDoubleSHA256 creates a new double SHA-256 hashing method.
+func NewDoubleSHA256() *DoubleSHA256 {
+	return &DoubleSHA256{}
+}
+
+// Name returns the identifier of double SHA-256 in the registry.
+func (h *DoubleSHA256) Name() string {
+	return "sha256d"
+}
+
+// HashLength returns the length of hashes generated by Hash() in bytes.
+func (h *DoubleSHA256) HashLength() int {
+	return sha256.Size
+}
+
+// Hash generates a double SHA-256 hash from input byte arrays.
+func (h *DoubleSHA256) Hash(data ...[]byte) []byte {
+	return sum(h.NewHasher(), data)
+}
+
+// NewHasher returns a hash.Hash computing the double SHA-256 hash of the data written to it.
+func (h *DoubleSHA256) NewHasher() gohash.Hash {
+	return doubleSHA256Hasher{sha256.New()}
+}
+
+// doubleSHA256Hasher hashes the SHA-256 digest of the data written to it once more when it is summed.
+type doubleSHA256Hasher struct {
+	gohash.Hash
+}
+
+// Sum appends the double SHA-256 hash of the data written so far to b.
+func (d doubleSHA256Hasher) Sum(b []byte) []byte {
+	var first [sha256.Size]byte
+	second := sha256.Sum256(d.Hash.Sum(first[:0]))
+	return append(b, second[:]...)
+}
+
+// sum writes the input byte arrays one after the other to the hash, and returns the digest.
+func sum(h gohash.Hash, data [][]byte) []byte {
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
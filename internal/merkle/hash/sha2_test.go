@@ -0,0 +1,75 @@
+package hash_test
+
+import (
+	"fmt"
+	hash2 "github.com/reactivejson/merkleTree/internal/merkle/hash"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSHA256(t *testing.T) {
+	tests := []struct {
+		input [][]byte
+		hash  []byte
+	}{
+		{
+			input: [][]byte{[]byte("abc")},
+			hash:  stringToByte("ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"),
+		},
+		{
+			input: [][]byte{[]byte("a"), []byte("b"), []byte("c")},
+			hash:  stringToByte("ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"),
+		},
+	}
+
+	hash := hash2.NewSHA256()
+	assert.Equal(t, 32, hash.HashLength())
+	for i, test := range tests {
+		assert.Equal(t, test.hash, hash.Hash(test.input...), fmt.Sprintf("failed at test %d", i))
+	}
+}
+
+func TestSHA512t256(t *testing.T) {
+	tests := []struct {
+		input [][]byte
+		hash  []byte
+	}{
+		{
+			input: [][]byte{[]byte("abc")},
+			hash:  stringToByte("53048e2681941ef99b2e29b76b4c7dabe4c2d0c634fc6d46e0e2f13107e7af23"),
+		},
+		{
+			input: [][]byte{[]byte("ab"), []byte("c")},
+			hash:  stringToByte("53048e2681941ef99b2e29b76b4c7dabe4c2d0c634fc6d46e0e2f13107e7af23"),
+		},
+	}
+
+	hash := hash2.NewSHA512t256()
+	assert.Equal(t, 32, hash.HashLength())
+	for i, test := range tests {
+		assert.Equal(t, test.hash, hash.Hash(test.input...), fmt.Sprintf("failed at test %d", i))
+	}
+}
+
+func TestDoubleSHA256(t *testing.T) {
+	tests := []struct {
+		input [][]byte
+		hash  []byte
+	}{
+		{
+			input: [][]byte{[]byte("hello")},
+			hash:  stringToByte("9595c9df90075148eb06860365df33584b75bff782a510c6cd4883a419833d50"),
+		},
+		{
+			input: [][]byte{[]byte("hel"), []byte("lo")},
+			hash:  stringToByte("9595c9df90075148eb06860365df33584b75bff782a510c6cd4883a419833d50"),
+		},
+	}
+
+	hash := hash2.NewDoubleSHA256()
+	assert.Equal(t, 32, hash.HashLength())
+	for i, test := range tests {
+		assert.Equal(t, test.hash, hash.Hash(test.input...), fmt.Sprintf("failed at test %d", i))
+	}
+}
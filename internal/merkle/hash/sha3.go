@@ -0,0 +1,71 @@
+package hash
+
+import (
+	gohash "hash"
+
+	"golang.org/x/crypto/sha3"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+const _sha3hashlength = 32
+
+// SHA3 is the SHA3-256 hashing method, as standardised in FIPS 202.
+type SHA3 struct{}
+
+// NewSHA3 creates a new SHA3-256 hashing method.
+func NewSHA3() *SHA3 {
+	return &SHA3{}
+}
+
+// Name returns the identifier of SHA3-256 in the registry.
+func (h *SHA3) Name() string {
+	return "sha3-256"
+}
+
+// HashLength returns the length of hashes generated by Hash() in bytes.
+func (h *SHA3) HashLength() int {
+	return _sha3hashlength
+}
+
+// Hash generates a SHA3-256 hash from input byte arrays.
+func (h *SHA3) Hash(data ...[]byte) []byte {
+	return sum(h.NewHasher(), data)
+}
+
+// NewHasher returns a hash.Hash computing the SHA3-256 hash of the data written to it.
+func (h *SHA3) NewHasher() gohash.Hash {
+	return sha3.New256()
+}
+
+// Keccak256 is the Keccak-256 hashing method used by Ethereum.
+// It differs from SHA3-256 by its padding, which predates FIPS 202.
+type Keccak256 struct{}
+
+// NewKeccak256 creates a new Keccak-256 hashing method.
+func NewKeccak256() *Keccak256 {
+	return &Keccak256{}
+}
+
+// Name returns the identifier of Keccak-256 in the registry.
+func (h *Keccak256) Name() string {
+	return "keccak256"
+}
+
+// HashLength returns the length of hashes generated by Hash() in bytes.
+func (h *Keccak256) HashLength() int {
+	return _sha3hashlength
+}
+
+// Hash generates a Keccak-256 hash from input byte arrays.
+func (h *Keccak256) Hash(data ...[]byte) []byte {
+	return sum(h.NewHasher(), data)
+}
+
+// NewHasher returns a hash.Hash computing the Keccak-256 hash of the data written to it.
+func (h *Keccak256) NewHasher() gohash.Hash {
+	return sha3.NewLegacyKeccak256()
+}
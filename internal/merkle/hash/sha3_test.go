@@ -0,0 +1,53 @@
+package hash_test
+
+import (
+	"fmt"
+	hash2 "github.com/reactivejson/merkleTree/internal/merkle/hash"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSHA3(t *testing.T) {
+	tests := []struct {
+		input [][]byte
+		hash  []byte
+	}{
+		{
+			input: [][]byte{[]byte("abc")},
+			hash:  stringToByte("3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532"),
+		},
+		{
+			input: [][]byte{[]byte("a"), []byte("bc")},
+			hash:  stringToByte("3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532"),
+		},
+	}
+
+	hash := hash2.NewSHA3()
+	assert.Equal(t, 32, hash.HashLength())
+	for i, test := range tests {
+		assert.Equal(t, test.hash, hash.Hash(test.input...), fmt.Sprintf("failed at test %d", i))
+	}
+}
+
+func TestKeccak256(t *testing.T) {
+	tests := []struct {
+		input [][]byte
+		hash  []byte
+	}{
+		{
+			input: [][]byte{{}},
+			hash:  stringToByte("c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"),
+		},
+		{
+			input: [][]byte{[]byte("abc")},
+			hash:  stringToByte("4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"),
+		},
+	}
+
+	hash := hash2.NewKeccak256()
+	assert.Equal(t, 32, hash.HashLength())
+	for i, test := range tests {
+		assert.Equal(t, test.hash, hash.Hash(test.input...), fmt.Sprintf("failed at test %d", i))
+	}
+}
@@ -0,0 +1,249 @@
+package merkletree
+
+import (
+	"bytes"
+	hash2 "github.com/reactivejson/merkleTree/internal/merkle/hash"
+	gohash "hash"
+	"sync"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// treeHasher hashes leaves and interior nodes, applying the domain separation and the shape of the tree.
+type treeHasher struct {
+	hash   hash2.HashType
+	domain DomainSeparation
+	shape  Shape
+	// sorted tells whether the children of a node are sorted before they are hashed.
+	sorted bool
+	// pool holds reusable hashers when the hash type is a hash2.Streamer, and is nil otherwise.
+	pool *sync.Pool
+	// batch is the hash type when it is a hash2.BatchHasher, and nil otherwise.
+	batch hash2.BatchHasher
+}
+
+// batchSize is the largest number of inputs handed to a hash2.BatchHasher at once.
+const batchSize = 64
+
+// newTreeHasher returns the hasher for the hash type with the given settings.
+func newTreeHasher(hash hash2.HashType, domain DomainSeparation, shape Shape, sorted bool) treeHasher {
+	h := treeHasher{hash: hash, domain: domain, shape: shape, sorted: sorted}
+	if streamer, ok := hash.(hash2.Streamer); ok {
+		h.pool = &sync.Pool{New: func() interface{} { return streamer.NewHasher() }}
+	}
+	h.batch, _ = hash.(hash2.BatchHasher)
+	return h
+}
+
+// validate returns an error if the hash type, being a hash2.Validator, can't hash the leaves of the data or the
+// nodes of the tree, whose children are digests of the hash type.
+func (h treeHasher) validate(data ...[]byte) error {
+	if _, ok := h.hash.(hash2.Validator); !ok {
+		return nil
+	}
+	digest := make([]byte, h.hash.HashLength())
+	if h.domain.Enabled() {
+		if err := hash2.Validate(h.hash, h.domain.NodePrefix, digest, digest); err != nil {
+			return err
+		}
+	} else if err := hash2.Validate(h.hash, digest, digest); err != nil {
+		return err
+	}
+	for _, d := range data {
+		if h.domain.Enabled() {
+			if err := hash2.Validate(h.hash, h.domain.LeafPrefix, d); err != nil {
+				return err
+			}
+		} else if err := hash2.Validate(h.hash, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// leaf hashes the raw input of a leaf.
+func (h treeHasher) leaf(data []byte) []byte {
+	return h.leafTo(nil, data)
+}
+
+// node hashes the concatenation of the left and right children of an interior node, in ascending order with
+// WithSortedPairs.
+func (h treeHasher) node(left, right []byte) []byte {
+	return h.nodeTo(nil, left, right)
+}
+
+// branch computes the node above the left and right children, where a nil child does not exist.
+// The right child only goes missing on the last node of an odd level, which never happens with ShapeZeroPad;
+// depending on the shape the left child is then paired with itself or promoted unchanged.
+func (h treeHasher) branch(left, right []byte) []byte {
+	return h.branchTo(nil, left, right)
+}
+
+// leafTo is leaf, writing the digest into the capacity of dst when the hash type is a hash2.Streamer.
+func (h treeHasher) leafTo(dst, data []byte) []byte {
+	if h.pool != nil {
+		return h.stream(dst, h.domain.LeafPrefix, data, nil)
+	}
+	if h.domain.Enabled() {
+		return h.hash.Hash(h.domain.LeafPrefix, data)
+	}
+	return h.hash.Hash(data)
+}
+
+// nodeTo is node, writing the digest into the capacity of dst when the hash type is a hash2.Streamer.
+func (h treeHasher) nodeTo(dst, left, right []byte) []byte {
+	if h.sorted && bytes.Compare(left, right) > 0 {
+		left, right = right, left
+	}
+	if h.pool != nil {
+		return h.stream(dst, h.domain.NodePrefix, left, right)
+	}
+	if h.domain.Enabled() {
+		return h.hash.Hash(h.domain.NodePrefix, left, right)
+	}
+	return h.hash.Hash(left, right)
+}
+
+// branchTo is branch, writing the digest into the capacity of dst when the hash type is a hash2.Streamer.
+func (h treeHasher) branchTo(dst, left, right []byte) []byte {
+	switch {
+	case left == nil:
+		return nil
+	case right != nil:
+		return h.nodeTo(dst, left, right)
+	case h.shape == ShapeDuplicateLast:
+		return h.nodeTo(dst, left, left)
+	default:
+		return left
+	}
+}
+
+// leavesTo hashes the leaves data[from:to] into dest[from:to], writing the digests into the slab as leafTo does.
+// With a hash2.BatchHasher, runs of leaves of the same length are hashed in batches.
+func (h treeHasher) leavesTo(dest [][]byte, slab []byte, data [][]byte, from, to int) {
+	if h.batch == nil {
+		for i := from; i < to; i++ {
+			dest[i] = h.leafTo(h.slot(slab, i), data[i])
+		}
+		return
+	}
+
+	prefix := h.domain.LeafPrefix
+	inputs := make([][]byte, 0, batchSize)
+	var buf []byte
+	for start := from; start < to; {
+		end := start + 1
+		for end < to && end-start < batchSize && len(data[end]) == len(data[start]) {
+			end++
+		}
+
+		inputs = inputs[:0]
+		if len(prefix) == 0 {
+			inputs = append(inputs, data[start:end]...)
+		} else {
+			// The prefixed leaves are copied into a buffer large enough for the whole run, so that it isn't moved.
+			n := len(prefix) + len(data[start])
+			if cap(buf) < (end-start)*n {
+				buf = make([]byte, 0, (end-start)*n)
+			}
+			buf = buf[:0]
+			for i := start; i < end; i++ {
+				buf = append(append(buf, prefix...), data[i]...)
+				inputs = append(inputs, buf[len(buf)-n:])
+			}
+		}
+		h.hashBatch(dest, slab, start, end, inputs)
+		start = end
+	}
+}
+
+// branchesTo computes the nodes [from, to) from their children, writing the digests into the slab as branchTo does.
+// With a hash2.BatchHasher, the nodes with two children (or one duplicated) are hashed in batches.
+func (h treeHasher) branchesTo(nodes [][]byte, slab []byte, from, to int) {
+	if h.batch == nil {
+		for i := from; i < to; i++ {
+			nodes[i] = h.branchTo(h.slot(slab, i), nodes[2*i], nodes[2*i+1])
+		}
+		return
+	}
+
+	size := h.hash.HashLength()
+	prefix := h.domain.NodePrefix
+	n := len(prefix) + 2*size
+	buf := make([]byte, batchSize*n)
+	inputs := make([][]byte, 0, batchSize)
+	for start := from; start < to; {
+		end := start
+		inputs = inputs[:0]
+		for end < to && end-start < batchSize {
+			left, right := nodes[2*end], nodes[2*end+1]
+			if right == nil && h.shape == ShapeDuplicateLast {
+				right = left
+			}
+			if left == nil || right == nil {
+				break
+			}
+			if h.sorted && bytes.Compare(left, right) > 0 {
+				left, right = right, left
+			}
+			input := buf[(end-start)*n : (end-start+1)*n]
+			copy(input, prefix)
+			copy(input[len(prefix):], left)
+			copy(input[len(prefix)+size:], right)
+			inputs = append(inputs, input)
+			end++
+		}
+
+		// A node without two children is absent or promoted.
+		if end == start {
+			nodes[start] = h.branchTo(h.slot(slab, start), nodes[2*start], nodes[2*start+1])
+			start++
+			continue
+		}
+		h.hashBatch(nodes, slab, start, end, inputs)
+		start = end
+	}
+}
+
+// hashBatch hashes the inputs of the nodes [start, end) with the hash2.BatchHasher into their slots of the slab.
+func (h treeHasher) hashBatch(dest [][]byte, slab []byte, start, end int, inputs [][]byte) {
+	size := h.hash.HashLength()
+	h.batch.HashBatch(slab[start*size:end*size], inputs)
+	for i := start; i < end; i++ {
+		dest[i] = h.slot(slab, i)[:size]
+	}
+}
+
+// stream hashes the prefix and the two parts with a pooled hasher, appending the digest to dst[:0].
+// The parts are written before the digest, so dst may be one of them.
+func (h treeHasher) stream(dst, prefix, a, b []byte) []byte {
+	w := h.pool.Get().(gohash.Hash)
+	w.Reset()
+	w.Write(prefix)
+	w.Write(a)
+	w.Write(b)
+	dst = w.Sum(dst[:0])
+	h.pool.Put(w)
+	return dst
+}
+
+// slab allocates the memory for n digests in one block, to be split with slot, when the hash type is a
+// hash2.Streamer or a hash2.BatchHasher. Otherwise every digest is allocated by the hash type and slab returns nil.
+func (h treeHasher) slab(n int) []byte {
+	if h.pool == nil && h.batch == nil {
+		return nil
+	}
+	return make([]byte, n*h.hash.HashLength())
+}
+
+// slot returns the empty, digest-sized i-th slot of a slab, or nil when there is no slab.
+func (h treeHasher) slot(slab []byte, i int) []byte {
+	if slab == nil {
+		return nil
+	}
+	size := h.hash.HashLength()
+	return slab[i*size : i*size : (i+1)*size]
+}
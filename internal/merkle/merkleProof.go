@@ -2,6 +2,7 @@ package merkletree
 
 import (
 	"bytes"
+	"errors"
 	"github.com/reactivejson/merkleTree/internal/merkle/hash"
 )
 
@@ -12,8 +13,65 @@ import (
 
 // MerkleProof is a proof of a Merkle tree.
 type MerkleProof struct {
-	Hashes [][]byte //2D byte array representing the hashes of nodes in the Merkle tree
-	Index  uint64   // The index of the input element for which the proof was generated
+	Hashes    [][]byte    //2D byte array representing the hashes of nodes in the Merkle tree
+	Index     uint64      // The index of the input element for which the proof was generated
+	LeafCount uint64      // The number of leaves in the tree, needed to verify proofs unless the shape is ShapeZeroPad
+	Params    *TreeParams // How the tree was built, or nil if the verifier has to know it
+	Root      []byte      // The root of the tree the proof was generated from, or nil; verification takes a trusted root instead
+	Leaf      []byte      // The hash of the proven leaf, or nil
+}
+
+// TreeParams describes how a tree was built, so that a proof carrying them can be verified by a third party
+// that doesn't know the tree beforehand.
+type TreeParams struct {
+	Algorithm   string           // The name of the hash type in the hash registry, empty if it has none
+	HashLength  int              // The length of the digests in bytes
+	Shape       Shape            // How levels with an odd number of nodes are completed
+	Domain      DomainSeparation // The leaf and node prefixes, if any
+	SortedPairs bool             // Whether the children of a node are sorted before they are hashed
+}
+
+// Params returns the parameters the tree was built with, as carried by its proofs.
+func (t *MerkleTree) Params() TreeParams {
+	return TreeParams{
+		Algorithm:   hash.NameOf(t.hash),
+		HashLength:  t.hash.HashLength(),
+		Shape:       t.shape,
+		Domain:      t.domain,
+		SortedPairs: t.sortedPairs,
+	}
+}
+
+// options returns the options to verify a proof of a tree built with these parameters.
+func (p *TreeParams) options() []Option {
+	opts := []Option{WithShape(p.Shape)}
+	if p.Domain.Enabled() {
+		opts = append(opts, WithDomainSeparation(p.Domain.LeafPrefix, p.Domain.NodePrefix))
+	}
+	if p.SortedPairs {
+		opts = append(opts, WithSortedPairs())
+	}
+	return opts
+}
+
+// check returns an error if a proof declaring these parameters is verified with another hash type or other options.
+func (p *TreeParams) check(hashType hash.HashType, cfg config) error {
+	if p.Algorithm != "" && p.Algorithm != hash.NameOf(hashType) {
+		return mismatch("the proof was built with another hash algorithm")
+	}
+	if p.HashLength != hashType.HashLength() {
+		return mismatch("the proof was built with another hash length")
+	}
+	if p.Shape != cfg.shape {
+		return mismatch("the proof was built with another tree shape")
+	}
+	if !bytes.Equal(p.Domain.LeafPrefix, cfg.domain.LeafPrefix) || !bytes.Equal(p.Domain.NodePrefix, cfg.domain.NodePrefix) {
+		return mismatch("the proof was built with other domain separation prefixes")
+	}
+	if p.SortedPairs != cfg.sortedPairs {
+		return mismatch("the proof was built with another pair ordering")
+	}
+	return nil
 }
 
 // NewProof generates a Merkle proof.
@@ -29,9 +87,42 @@ func NewProof(hashes [][]byte, index uint64) *MerkleProof {
 // be verified.  Note that this does not require the Merkle tree to verify the proof, only its root; this allows for checking
 // against historical trees without having to instantiate them.
 //
-// This returns true if the proof is verified, otherwise false.
-func VerifyMProof(data []byte, proof *MerkleProof, root []byte, hashType hash.HashType) (bool, error) {
-	proofHash := proofHash(data, proof, hashType)
+// The options must match the ones the tree was built with, e.g. WithRFC6962Prefixes or WithShape. When the proof
+// carries the parameters of its tree, a different hash type or different options are an error; see Verify.
+//
+// This returns true if the proof is verified, otherwise false. A proof that can't be verified is an error matching
+// ErrMalformedProof, such as ErrHashLength or ErrIndexOutOfRange, and a proof built with other options an error
+// matching ErrParamsMismatch.
+func VerifyMProof(data []byte, proof *MerkleProof, root []byte, hashType hash.HashType, opts ...Option) (bool, error) {
+	if proof == nil {
+		return false, ErrNilProof
+	}
+	if hashType == nil {
+		return false, ErrNilHashType
+	}
+	cfg := newConfig(opts)
+	if err := cfg.validate(); err != nil {
+		return false, err
+	}
+	if proof.Params != nil {
+		if err := proof.Params.check(hashType, cfg); err != nil {
+			return false, err
+		}
+	}
+	if err := checkLengths(hashType, root, proof.Hashes); err != nil {
+		return false, err
+	}
+	if len(proof.Hashes) > maxProofDepth {
+		return false, ErrProofTooDeep
+	}
+	hasher := cfg.hasher(hashType)
+	if err := hasher.validate(data); err != nil {
+		return false, err
+	}
+	proofHash, err := proofHash(data, proof, hasher)
+	if err != nil {
+		return false, err
+	}
 	if bytes.Equal(root, proofHash) {
 		// If the hash in the root matches the proof hash, this line returns true and a nil error.
 		return true, nil
@@ -41,30 +132,126 @@ func VerifyMProof(data []byte, proof *MerkleProof, root []byte, hashType hash.Ha
 	return false, nil
 }
 
+// Verify verifies the proof for a piece of input against the root, with the hash type and options described by the
+// parameters of the proof. The hash type is resolved from the hash registry, so proofs of trees built with a hash type
+// that isn't registered, such as keyed BLAKE3, can only be verified with VerifyMProof by a holder of the key.
+//
+// This returns true if the proof is verified, otherwise false.
+func (p *MerkleProof) Verify(data []byte, root []byte) (bool, error) {
+	if p == nil {
+		return false, ErrNilProof
+	}
+	if p.Params == nil {
+		return false, errors.New("the proof has no tree parameters")
+	}
+	if p.Params.Algorithm == "" {
+		return false, errors.New("the proof has no hash algorithm")
+	}
+	hashType, err := hash.ByName(p.Params.Algorithm)
+	if err != nil {
+		return false, err
+	}
+	if hashType.HashLength() != p.Params.HashLength {
+		if hashType, err = hash.WithLength(hashType, p.Params.HashLength); err != nil {
+			return false, err
+		}
+	}
+	return VerifyMProof(data, p, root, hashType, p.Params.options()...)
+}
+
+// checkLengths checks that the root and the hashes of a proof are all digests of the hash type, which can be hashed
+// by a hash.Validator.
+// A proof mixing digest lengths, or built with another length than the one of the hash type, is rejected
+// instead of merely failing to verify.
+func checkLengths(hashType hash.HashType, root []byte, hashes ...[][]byte) error {
+	length := hashType.HashLength()
+	if len(root) != length {
+		return ErrRootLength
+	}
+	if hash.Validate(hashType, root) != nil {
+		return errNotDigest
+	}
+	for _, list := range hashes {
+		for _, h := range list {
+			if len(h) != length {
+				return ErrHashLength
+			}
+			if hash.Validate(hashType, h) != nil {
+				return errNotDigest
+			}
+		}
+	}
+	return nil
+}
+
 // proofHash generates a proof hash for a piece of input using the provided Merkle proof and hash function.
-func proofHash(data []byte, proof *MerkleProof, hashType hash.HashType) []byte {
+func proofHash(data []byte, proof *MerkleProof, hasher treeHasher) ([]byte, error) {
+	if proof.LeafCount != 0 && proof.Index >= proof.LeafCount {
+		return nil, ErrIndexOutOfRange
+	}
+	if hasher.shape != ShapeZeroPad {
+		return shapedProofHash(data, proof, hasher)
+	}
+	// The leaf at the index must be reachable from the root in as many levels as the proof has hashes.
+	if len(proof.Hashes) < maxProofDepth && proof.Index>>uint(len(proof.Hashes)) != 0 {
+		return nil, ErrIndexOutOfRange
+	}
 
 	var proofHash []byte
 
 	// Generate the initial hash by hashing the input with the provided hash function.
-	proofHash = hashType.Hash(data)
+	proofHash = hasher.leaf(data)
 
 	// Calculate the starting index in the proof array based on the number of hashes in the proof.
 	index := proof.Index + (1 << uint(len(proof.Hashes)))
 
 	// Loop over each hash in the proof array, combining them with the proof hash based on whether the current index is even or odd.
+	// The proof hash is only ever read to compute the next one, so its memory is reused for it.
 	for _, hash := range proof.Hashes {
 		if index%2 == 0 {
 			// If the index is even, hash the proof hash and the current hash together.
-			proofHash = hashType.Hash(proofHash, hash)
+			proofHash = hasher.nodeTo(proofHash, proofHash, hash)
 		} else {
 			// If the index is odd, hash the current hash and the proof hash together.
-			proofHash = hashType.Hash(hash, proofHash)
+			proofHash = hasher.nodeTo(proofHash, hash, proofHash)
 		}
 		// Shift the index right by one bit, effectively dividing it by 2 and rounding down to the nearest integer.
 		index >>= 1
 	}
 
 	// Return the final proof hash.
-	return proofHash
+	return proofHash, nil
+}
+
+// shapedProofHash generates a proof hash for a tree whose odd levels are not padded.
+// The last node of an odd level has no sibling in the proof, so the leaf count tells at which levels to complete
+// the level instead of consuming a hash.
+func shapedProofHash(data []byte, proof *MerkleProof, hasher treeHasher) ([]byte, error) {
+	if proof.LeafCount == 0 {
+		return nil, errNoLeafCount
+	}
+
+	proofHash := hasher.leaf(data)
+	hashes := proof.Hashes
+
+	// index is the position of the current node in its level, and count the number of nodes in that level.
+	for index, count := proof.Index, proof.LeafCount; count > 1; index, count = index/2, (count+1)/2 {
+		switch {
+		case index^1 >= count:
+			proofHash = hasher.branchTo(proofHash, proofHash, nil)
+		case len(hashes) == 0:
+			return nil, errNotEnoughHashes
+		case index%2 == 0:
+			proofHash = hasher.nodeTo(proofHash, proofHash, hashes[0])
+			hashes = hashes[1:]
+		default:
+			proofHash = hasher.nodeTo(proofHash, hashes[0], proofHash)
+			hashes = hashes[1:]
+		}
+	}
+	if len(hashes) != 0 {
+		return nil, errTooManyHashes
+	}
+
+	return proofHash, nil
 }
@@ -0,0 +1,229 @@
+package merkletree_test
+
+import (
+	"fmt"
+	merkletree "github.com/reactivejson/merkleTree/internal/merkle"
+	"github.com/reactivejson/merkleTree/internal/merkle/hash"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProofParams(t *testing.T) {
+	tree, err := merkletree.NewTree(leaves(5), sha256, merkletree.WithShape(merkletree.ShapeRFC6962))
+	assert.NoError(t, err)
+
+	proof, err := tree.GenerateMProofAt(3)
+	assert.NoError(t, err)
+	assert.Equal(t, &merkletree.TreeParams{
+		Algorithm:  "sha256",
+		HashLength: 32,
+		Shape:      merkletree.ShapeRFC6962,
+		Domain:     merkletree.DomainSeparation{LeafPrefix: []byte{0x00}, NodePrefix: []byte{0x01}},
+	}, proof.Params)
+	assert.Equal(t, tree.Params(), *proof.Params)
+}
+
+func TestProofVerify(t *testing.T) {
+	blake3x16, err := hash.NewBlake3WithLength(16)
+	assert.NoError(t, err)
+	tests := []struct {
+		hashType hash.HashType
+		opts     []merkletree.Option
+	}{
+		{hashType: blake3},
+		{hashType: sha256, opts: []merkletree.Option{merkletree.WithShape(merkletree.ShapeRFC6962)}},
+		{hashType: hash.NewKeccak256(), opts: []merkletree.Option{merkletree.WithShape(merkletree.ShapeDuplicateLast)}},
+		{hashType: blake3x16, opts: []merkletree.Option{merkletree.WithDomainSeparation([]byte("leaf"), []byte("node"))}},
+	}
+
+	data := leaves(7)
+	for i, test := range tests {
+		tree, err := merkletree.NewTree(data, test.hashType, test.opts...)
+		assert.NoError(t, err)
+		for j, d := range data {
+			proof, err := tree.GenerateMProofAt(uint64(j))
+			assert.NoError(t, err)
+			verified, err := proof.Verify(d, tree.MerkleRoot())
+			assert.NoError(t, err)
+			assert.True(t, verified, fmt.Sprintf("failed to verify proof at test %d input %d", i, j))
+			verified, err = proof.Verify([]byte("other"), tree.MerkleRoot())
+			assert.NoError(t, err)
+			assert.False(t, verified, fmt.Sprintf("verified wrong input at test %d input %d", i, j))
+		}
+	}
+}
+
+func TestProofVerifyErrors(t *testing.T) {
+	tree, err := merkletree.NewTree(leaves(4), blake3)
+	assert.NoError(t, err)
+	root := tree.MerkleRoot()
+
+	proof := merkletree.NewProof(nil, 0)
+	_, err = proof.Verify([]byte("leaf-0"), root)
+	assert.EqualError(t, err, "the proof has no tree parameters")
+
+	proof, err = tree.GenerateMProofAt(0)
+	assert.NoError(t, err)
+	proof.Params.Algorithm = ""
+	_, err = proof.Verify([]byte("leaf-0"), root)
+	assert.EqualError(t, err, "the proof has no hash algorithm")
+
+	proof.Params.Algorithm = "md5"
+	_, err = proof.Verify([]byte("leaf-0"), root)
+	assert.EqualError(t, err, `unknown hash type "md5"`)
+
+	proof.Params.Algorithm = "sha256"
+	proof.Params.HashLength = 20
+	_, err = proof.Verify([]byte("leaf-0"), root)
+	assert.EqualError(t, err, "the hash length is fixed to 32 bytes")
+
+	// A keyed tree can't be verified without its key.
+	var key [32]byte
+	keyed, err := merkletree.NewTree(leaves(4), hash.NewBlake3Keyed(key))
+	assert.NoError(t, err)
+	proof, err = keyed.GenerateMProofAt(0)
+	assert.NoError(t, err)
+	_, err = proof.Verify([]byte("leaf-0"), keyed.MerkleRoot())
+	assert.EqualError(t, err, `unknown hash type "blake3-keyed"`)
+}
+
+func TestProofParamsMismatch(t *testing.T) {
+	tree, err := merkletree.NewTree(leaves(5), blake3, merkletree.WithShape(merkletree.ShapePromoteOdd))
+	assert.NoError(t, err)
+	proof, err := tree.GenerateMProofAt(2)
+	assert.NoError(t, err)
+	root := tree.MerkleRoot()
+
+	blake3x16, err := hash.NewBlake3WithLength(16)
+	assert.NoError(t, err)
+	tests := []struct {
+		hashType hash.HashType
+		opts     []merkletree.Option
+		err      string
+	}{
+		{ // 0
+			hashType: sha256,
+			opts:     []merkletree.Option{merkletree.WithShape(merkletree.ShapePromoteOdd)},
+			err:      "the proof was built with another hash algorithm",
+		},
+		{ // 1
+			hashType: blake3x16,
+			opts:     []merkletree.Option{merkletree.WithShape(merkletree.ShapePromoteOdd)},
+			err:      "the proof was built with another hash length",
+		},
+		{ // 2
+			hashType: blake3,
+			err:      "the proof was built with another tree shape",
+		},
+		{ // 3
+			hashType: blake3,
+			opts:     []merkletree.Option{merkletree.WithShape(merkletree.ShapeRFC6962)},
+			err:      "the proof was built with another tree shape",
+		},
+		{ // 4
+			hashType: blake3,
+			opts:     []merkletree.Option{merkletree.WithShape(merkletree.ShapePromoteOdd), merkletree.WithRFC6962Prefixes()},
+			err:      "the proof was built with other domain separation prefixes",
+		},
+	}
+
+	for i, test := range tests {
+		verified, err := merkletree.VerifyMProof([]byte("leaf-2"), proof, root, test.hashType, test.opts...)
+		assert.EqualError(t, err, test.err, fmt.Sprintf("unexpected error at test %d", i))
+		assert.ErrorIs(t, err, merkletree.ErrParamsMismatch, fmt.Sprintf("unexpected error kind at test %d", i))
+		assert.False(t, verified, fmt.Sprintf("verified proof at test %d", i))
+	}
+
+	verified, err := merkletree.VerifyMProof([]byte("leaf-2"), proof, root, blake3, merkletree.WithShape(merkletree.ShapePromoteOdd))
+	assert.NoError(t, err)
+	assert.True(t, verified)
+}
+
+func TestVerifyMProofErrors(t *testing.T) {
+	tree, err := merkletree.NewTree(leaves(4), blake3)
+	assert.NoError(t, err)
+	root := tree.MerkleRoot()
+	valid, err := tree.GenerateMProofAt(1)
+	assert.NoError(t, err)
+	deep := make([][]byte, 65)
+	for i := range deep {
+		deep[i] = root
+	}
+
+	tests := []struct {
+		proof    *merkletree.MerkleProof
+		root     []byte
+		hashType hash.HashType
+		opts     []merkletree.Option
+		err      error
+	}{
+		{ // 0
+			root:     root,
+			hashType: blake3,
+			err:      merkletree.ErrNilProof,
+		},
+		{ // 1
+			proof: valid,
+			root:  root,
+			err:   merkletree.ErrNilHashType,
+		},
+		{ // 2
+			proof:    valid,
+			root:     root[:16],
+			hashType: blake3,
+			err:      merkletree.ErrRootLength,
+		},
+		{ // 3
+			proof:    merkletree.NewProof([][]byte{root, root[:31]}, 1),
+			root:     root,
+			hashType: blake3,
+			err:      merkletree.ErrHashLength,
+		},
+		{ // 4
+			proof:    merkletree.NewProof([][]byte{root, root}, 4),
+			root:     root,
+			hashType: blake3,
+			err:      merkletree.ErrIndexOutOfRange,
+		},
+		{ // 5
+			proof:    &merkletree.MerkleProof{Hashes: [][]byte{root, root}, Index: 4, LeafCount: 4},
+			root:     root,
+			hashType: blake3,
+			err:      merkletree.ErrIndexOutOfRange,
+		},
+		{ // 6
+			proof:    merkletree.NewProof(deep, 0),
+			root:     root,
+			hashType: blake3,
+			err:      merkletree.ErrProofTooDeep,
+		},
+		{ // 7
+			proof:    merkletree.NewProof([][]byte{root}, 0),
+			root:     root,
+			hashType: blake3,
+			opts:     []merkletree.Option{merkletree.WithShape(merkletree.ShapePromoteOdd)},
+			err:      merkletree.ErrMalformedProof,
+		},
+	}
+
+	for i, test := range tests {
+		verified, err := merkletree.VerifyMProof([]byte("leaf-1"), test.proof, test.root, test.hashType, test.opts...)
+		assert.ErrorIs(t, err, test.err, fmt.Sprintf("unexpected error at test %d", i))
+		assert.False(t, verified, fmt.Sprintf("verified proof at test %d", i))
+		if i >= 2 {
+			assert.ErrorIs(t, err, merkletree.ErrMalformedProof, fmt.Sprintf("unexpected error kind at test %d", i))
+		}
+	}
+
+	// A well formed proof of other data, or against another root, is not an error.
+	verified, err := merkletree.VerifyMProof([]byte("leaf-2"), valid, root, blake3)
+	assert.NoError(t, err)
+	assert.False(t, verified)
+	verified, err = merkletree.VerifyMProof([]byte("leaf-1"), valid, valid.Hashes[0], blake3)
+	assert.NoError(t, err)
+	assert.False(t, verified)
+
+	_, err = (*merkletree.MerkleProof)(nil).Verify([]byte("leaf-1"), root)
+	assert.ErrorIs(t, err, merkletree.ErrNilProof)
+}
@@ -1,10 +1,11 @@
 package merkletree
 
 import (
-	"bytes"
 	"errors"
 	hash2 "github.com/reactivejson/merkleTree/internal/merkle/hash"
 	"math"
+	"sort"
+	"sync"
 )
 
 /**
@@ -16,25 +17,78 @@ import (
 type MerkleTree struct {
 	// hash is a pointer to the hashing struct
 	hash hash2.HashType
+	// domain holds the leaf and node prefixes the tree was built with
+	domain DomainSeparation
+	// shape is how levels with an odd number of nodes are completed
+	shape Shape
+	// sortedPairs tells whether the children of a node are sorted before they are hashed
+	sortedPairs bool
 	// data is the data from which the Merkle tree is created
 	data [][]byte
-	// nodes are the leaf and branch nodes of the Merkle tree
+	// nodes are the leaf and branch nodes of the Merkle tree; unless the shape is ShapeZeroPad, nodes that don't exist are nil
 	nodes [][]byte
+	// leafIndex maps the hash of each leaf to the positions holding it, in ascending order
+	leafIndex map[string][]uint64
+	// workers is the number of goroutines used to hash large levels
+	workers int
+	// pool holds reusable hashers when the hash type is a hash2.Streamer
+	pool *sync.Pool
 }
 
-// dataIndex returns Index of the data in the MerkleTree.
-func (t *MerkleTree) dataIndex(input []byte) (uint64, error) {
-	for i, data := range t.data {
-		if bytes.Equal(data, input) {
-			return uint64(i), nil
-		}
+// dataIndexes returns the indexes of the data in the MerkleTree, in ascending order.
+func (t *MerkleTree) dataIndexes(input []byte) ([]uint64, error) {
+	hasher := t.hasher()
+	if err := hasher.validate(input); err != nil {
+		return nil, err
+	}
+	indexes := t.leafIndex[string(hasher.leaf(input))]
+	if len(indexes) == 0 {
+		return nil, errors.New("data not found")
+	}
+	return indexes, nil
+}
+
+// indexLeaves rebuilds the lookup of leaf hashes to positions from the leaf nodes.
+func (t *MerkleTree) indexLeaves() {
+	width := len(t.nodes) / 2
+	t.leafIndex = make(map[string][]uint64, len(t.data))
+	for i := range t.data {
+		key := string(t.nodes[width+i])
+		t.leafIndex[key] = append(t.leafIndex[key], uint64(i))
+	}
+}
+
+// addIndex records that the leaf with the given hash is at the index.
+func (t *MerkleTree) addIndex(leaf []byte, index uint64) {
+	key := string(leaf)
+	indexes := t.leafIndex[key]
+	pos := sort.Search(len(indexes), func(i int) bool { return indexes[i] >= index })
+	indexes = append(indexes, 0)
+	copy(indexes[pos+1:], indexes[pos:])
+	indexes[pos] = index
+	t.leafIndex[key] = indexes
+}
+
+// removeIndex forgets that the leaf with the given hash is at the index.
+func (t *MerkleTree) removeIndex(leaf []byte, index uint64) {
+	key := string(leaf)
+	indexes := t.leafIndex[key]
+	pos := sort.Search(len(indexes), func(i int) bool { return indexes[i] >= index })
+	if pos == len(indexes) || indexes[pos] != index {
+		return
 	}
-	return 0, errors.New("data not found")
+	if len(indexes) == 1 {
+		delete(t.leafIndex, key)
+		return
+	}
+	t.leafIndex[key] = append(indexes[:pos], indexes[pos+1:]...)
 }
 
 // NewTree creates a new Merkle tree using the provided raw input and default hash type.
 // data must contain at least one element for it to be valid.
-func NewTree(data [][]byte, hash hash2.HashType) (*MerkleTree, error) {
+// Options such as WithRFC6962Prefixes or WithShape change how leaves and nodes are hashed, and the same options must be
+// given to VerifyMProof when checking proofs from the tree.
+func NewTree(data [][]byte, hash hash2.HashType, opts ...Option) (*MerkleTree, error) {
 
 	if len(data) == 0 {
 		return nil, errors.New("the merkle tree should contains at least 1 piece of input")
@@ -42,6 +96,14 @@ func NewTree(data [][]byte, hash hash2.HashType) (*MerkleTree, error) {
 	if hash == nil {
 		return nil, errors.New("please specify hash algo")
 	}
+	cfg := newConfig(opts)
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	hasher := cfg.hasher(hash)
+	if err := hasher.validate(data...); err != nil {
+		return nil, err
+	}
 
 	// starts by calculating the number of branches that the tree will have.
 	//This is done by finding the next power of 2 greater than or equal to the number of input elements, using the ceil of the log2 of the input length.
@@ -54,34 +116,48 @@ func NewTree(data [][]byte, hash hash2.HashType) (*MerkleTree, error) {
 	createLeaves(
 		data,
 		nodes[branchesLen:branchesLen+len(data)],
-		hash,
+		hasher,
+		cfg.workers,
 	)
-	// Pad the space left after the leaves.
-	for i := len(data) + branchesLen; i < len(nodes); i++ {
-		nodes[i] = make([]byte, hash.HashLength())
+	// Pad the space left after the leaves. With the other shapes the space is left empty.
+	if cfg.shape == ShapeZeroPad {
+		pad := make([]byte, hash.HashLength())
+		for i := len(data) + branchesLen; i < len(nodes); i++ {
+			nodes[i] = pad
+		}
 	}
 
 	// Branches.
 	createNonLeaves(
 		nodes,
-		hash,
+		hasher,
 		branchesLen,
+		cfg.workers,
 	)
 
 	tree := &MerkleTree{
-		hash:  hash,
-		nodes: nodes,
-		data:  data,
+		hash:        hash,
+		domain:      cfg.domain,
+		shape:       cfg.shape,
+		sortedPairs: cfg.sortedPairs,
+		nodes:       nodes,
+		data:        data,
+		workers:     cfg.workers,
+		pool:        hasher.pool,
 	}
+	tree.indexLeaves()
 
 	return tree, nil
 }
 
 // Hashes the input slice, placing the result hashes into dest.
-func createLeaves(data [][]byte, dest [][]byte, hash hash2.HashType) {
-	for i := range data {
-		dest[i] = hash.Hash(data[i])
-	}
+// With a hash2.Streamer the hashes are written into a single block of memory instead of being allocated one by one,
+// and with a hash2.BatchHasher the leaves are hashed several at a time.
+func createLeaves(data [][]byte, dest [][]byte, hasher treeHasher, workers int) {
+	slab := hasher.slab(len(data))
+	parallel(len(data), workers, func(from, to int) {
+		hasher.leavesTo(dest, slab, data, from, to)
+	})
 }
 
 // Create the non-leaf nodes from the existing leaf input.
@@ -89,37 +165,62 @@ func createLeaves(data [][]byte, dest [][]byte, hash hash2.HashType) {
 // This function creates the non-leaf nodes of the tree by computing the hash of each pair of child nodes and storing
 // it in the corresponding parent node in the slice of nodes.
 // The process continues recursively until there is only one node left, which represents the root of the tree.
-func createNonLeaves(nodes [][]byte, hash hash2.HashType, leafOffset int) {
-	//  iterates through the nodes from the last leaf node to the root node.
-	for i := leafOffset - 1; i > 0; i-- {
-		// For each non-leaf node, it retrieves the left and right child nodes by accessing the nodes slice with the formula i2 and i2+1, respectively.
-		left := nodes[i*2]
-		right := nodes[i*2+1]
-
-		// computes the hash of the concatenation of the left and right child nodes
-		nodes[i] = hash.Hash(left, right)
-
+// Each level only depends on the one below it, so the nodes of a level are hashed concurrently when workers > 1.
+// As with createLeaves, a hash2.Streamer writes all the branches into a single block of memory, and a
+// hash2.BatchHasher hashes several branches at a time.
+func createNonLeaves(nodes [][]byte, hasher treeHasher, leafOffset int, workers int) {
+	slab := hasher.slab(leafOffset)
+	//  iterates through the levels from the one above the leaves to the root node; level w starts at index w.
+	for w := leafOffset / 2; w >= 1; w /= 2 {
+		level := w
+		parallel(level, workers, func(from, to int) {
+			// For each non-leaf node i, the left and right child nodes are at i*2 and i*2+1, respectively: it holds the
+			// hash of their concatenation, or completes an odd level.
+			hasher.branchesTo(nodes, slab, level+from, level+to)
+		})
 	}
 }
 
 // GenerateMProof generates the proof for a piece of input.
 // If the input is not present in the tree this will return an error.
 // If the input is present in the tree this will return the hashes for each level in the tree and the index of the value in the tree.
+// When the input is present more than once, the proof is for its first position; see GenerateMProofs.
 func (t *MerkleTree) GenerateMProof(data []byte) (*MerkleProof, error) {
 	// Find the index of the input
-	index, err := t.dataIndex(data)
+	indexes, err := t.dataIndexes(data)
+	if err != nil {
+		return nil, err
+	}
+	return t.GenerateMProofAt(indexes[0])
+}
+
+// GenerateMProofs generates a proof for every position holding a piece of input, in ascending order of index.
+// If the input is not present in the tree this will return an error.
+func (t *MerkleTree) GenerateMProofs(data []byte) ([]*MerkleProof, error) {
+	indexes, err := t.dataIndexes(data)
 	if err != nil {
 		return nil, err
 	}
+	proofs := make([]*MerkleProof, len(indexes))
+	for i, index := range indexes {
+		if proofs[i], err = t.GenerateMProofAt(index); err != nil {
+			return nil, err
+		}
+	}
+	return proofs, nil
+}
+
+// GenerateMProofAt generates the proof for the leaf at the given index, without looking up its input.
+func (t *MerkleTree) GenerateMProofAt(index uint64) (*MerkleProof, error) {
+	if index >= uint64(len(t.data)) {
+		return nil, errors.New("index out of bounds")
+	}
 
 	// calculates the length of the proof by computing the number of levels required to reach the root of the tree
 	proofLen := int(math.Ceil(math.Log2(float64(len(t.data)))))
 
 	//  It initializes an empty slice to hold the hashes of the proof.
-	hashes := make([][]byte, proofLen)
-
-	// It initializes the current index to 0.
-	currentIndex := 0
+	hashes := make([][]byte, 0, proofLen)
 
 	minIndex := uint64(1)
 
@@ -128,11 +229,42 @@ func (t *MerkleTree) GenerateMProof(data []byte) (*MerkleProof, error) {
 	//At each iteration, the code computes the sibling hash of the current node and stores it in the hashes slice.
 	// The ^1 operation is a bitwise XOR which toggles the last bit of the index, which selects the sibling node in the tree.
 	for i := index + uint64(len(t.nodes)/2); i > minIndex; i /= 2 {
+		// The last node of an odd level has no sibling, the verifier completes the level from the leaf count.
+		if t.nodes[i^1] == nil {
+			continue
+		}
 		//  stores the computed sibling hash in the hashes slice.
-		hashes[currentIndex] = t.nodes[i^1]
-		currentIndex++
+		hashes = append(hashes, t.nodes[i^1])
 	}
-	return NewProof(hashes, index), nil
+	proof := NewProof(hashes, index)
+	proof.LeafCount = uint64(len(t.data))
+	params := t.Params()
+	proof.Params = &params
+	proof.Root = t.MerkleRoot()
+	proof.Leaf = t.nodes[index+uint64(len(t.nodes)/2)]
+	return proof, nil
+}
+
+// DomainSeparation returns the leaf and node prefixes the tree was built with.
+// Roots built with and without domain separation are never comparable.
+func (t *MerkleTree) DomainSeparation() DomainSeparation {
+	return t.domain
+}
+
+// HashType returns the hash type the tree was built with.
+func (t *MerkleTree) HashType() hash2.HashType {
+	return t.hash
+}
+
+// Shape returns how the tree completes levels with an odd number of nodes.
+func (t *MerkleTree) Shape() Shape {
+	return t.shape
+}
+
+// hasher returns the leaf and node hasher of the tree.
+func (t *MerkleTree) hasher() treeHasher {
+	batch, _ := t.hash.(hash2.BatchHasher)
+	return treeHasher{hash: t.hash, domain: t.domain, shape: t.shape, sorted: t.sortedPairs, pool: t.pool, batch: batch}
 }
 
 // MerkleRoot returns the Merkle root (hash of the root node) of the tree.
@@ -149,14 +281,21 @@ func (t *MerkleTree) UpdateLeaf(index uint64, newData []byte) error {
 		return errors.New("index out of bounds")
 	}
 
+	hasher := t.hasher()
+	if err := hasher.validate(newData); err != nil {
+		return err
+	}
+
 	// Hash the new input.
-	newLeaf := t.hash.Hash(newData)
+	newLeaf := hasher.leaf(newData)
 
 	// Replace old input with new input.
 	t.data[index] = newData
 
 	// Update nodes in the path from the updated leaf to the root.
 	nodeIndex := index + uint64(len(t.nodes)/2)
+	t.removeIndex(t.nodes[nodeIndex], index)
+	t.addIndex(newLeaf, index)
 	t.nodes[nodeIndex] = newLeaf
 	// Loop through the path from the updated leaf to the root.
 	for nodeIndex > 1 {
@@ -175,11 +314,11 @@ func (t *MerkleTree) UpdateLeaf(index uint64, newData []byte) error {
 		if nodeIndex%2 == 0 {
 			// If it is the left child, calculate the hash of the parent node by hashing
 			// the current node's hash and its sibling's hash.
-			t.nodes[parentIndex] = t.hash.Hash(t.nodes[nodeIndex], t.nodes[siblingIndex])
+			t.nodes[parentIndex] = hasher.branch(t.nodes[nodeIndex], t.nodes[siblingIndex])
 		} else {
 			// If it is the right child, calculate the hash of the parent node by hashing
 			// its sibling's hash and the current node's hash.
-			t.nodes[parentIndex] = t.hash.Hash(t.nodes[siblingIndex], t.nodes[nodeIndex])
+			t.nodes[parentIndex] = hasher.branch(t.nodes[siblingIndex], t.nodes[nodeIndex])
 		}
 
 		nodeIndex = parentIndex
@@ -1,6 +1,7 @@
 package merkletree_test
 
 import (
+	"bytes"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -8,6 +9,7 @@ import (
 	"github.com/reactivejson/merkleTree/internal/merkle/hash"
 	"os"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -153,3 +155,417 @@ func write(f, s string) {
 		panic(err)
 	}
 }
+
+func TestDomainSeparation(t *testing.T) {
+	data := [][]byte{
+		[]byte("Foo"),
+		[]byte("Bar"),
+		[]byte("Baz"),
+		[]byte("Qux"),
+	}
+
+	plain, err := merkletree.NewTree(data, blake3)
+	assert.NoError(t, err)
+	tree, err := merkletree.NewTree(data, blake3, merkletree.WithRFC6962Prefixes())
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, plain.MerkleRoot(), tree.MerkleRoot())
+	assert.Equal(t, []byte{0x00}, tree.DomainSeparation().LeafPrefix)
+	assert.Equal(t, []byte{0x01}, tree.DomainSeparation().NodePrefix)
+	assert.False(t, plain.DomainSeparation().Enabled())
+
+	// leaf = H(0x00 || data), node = H(0x01 || left || right)
+	left := blake3.Hash([]byte{0x01}, blake3.Hash([]byte{0x00}, data[0]), blake3.Hash([]byte{0x00}, data[1]))
+	right := blake3.Hash([]byte{0x01}, blake3.Hash([]byte{0x00}, data[2]), blake3.Hash([]byte{0x00}, data[3]))
+	assert.Equal(t, blake3.Hash([]byte{0x01}, left, right), tree.MerkleRoot())
+
+	for i, d := range data {
+		proof, err := tree.GenerateMProof(d)
+		assert.NoError(t, err)
+		ok, err := merkletree.VerifyMProof(d, proof, tree.MerkleRoot(), blake3, merkletree.WithRFC6962Prefixes())
+		assert.NoError(t, err)
+		assert.True(t, ok, fmt.Sprintf("failed to verify proof for input %d", i))
+		ok, err = merkletree.VerifyMProof(d, proof, tree.MerkleRoot(), blake3)
+		assert.EqualError(t, err, "the proof was built with other domain separation prefixes")
+		assert.False(t, ok, fmt.Sprintf("verified proof without prefixes for input %d", i))
+
+		// Without the parameters of its tree the proof is checked as given, and fails.
+		bare := *proof
+		bare.Params = nil
+		ok, err = merkletree.VerifyMProof(d, &bare, tree.MerkleRoot(), blake3)
+		assert.NoError(t, err)
+		assert.False(t, ok, fmt.Sprintf("verified proof without prefixes for input %d", i))
+	}
+
+	assert.NoError(t, tree.UpdateLeaf(1, []byte("Quux")))
+	proof, err := tree.GenerateMProof([]byte("Quux"))
+	assert.NoError(t, err)
+	ok, err := merkletree.VerifyMProof([]byte("Quux"), proof, tree.MerkleRoot(), blake3, merkletree.WithRFC6962Prefixes())
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestDomainSeparationSecondPreimage(t *testing.T) {
+	data := [][]byte{
+		[]byte("Foo"),
+		[]byte("Bar"),
+		[]byte("Baz"),
+		[]byte("Qux"),
+	}
+
+	// Without domain separation an interior node can be passed off as a leaf.
+	plain, err := merkletree.NewTree(data, blake3)
+	assert.NoError(t, err)
+	forged := append(blake3.Hash(data[0]), blake3.Hash(data[1])...)
+	sibling := blake3.Hash(blake3.Hash(data[2]), blake3.Hash(data[3]))
+	ok, err := merkletree.VerifyMProof(forged, merkletree.NewProof([][]byte{sibling}, 0), plain.MerkleRoot(), blake3)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// With domain separation the same forgery is rejected.
+	tree, err := merkletree.NewTree(data, blake3, merkletree.WithRFC6962Prefixes())
+	assert.NoError(t, err)
+	forged = append(blake3.Hash([]byte{0x00}, data[0]), blake3.Hash([]byte{0x00}, data[1])...)
+	sibling = blake3.Hash([]byte{0x01}, blake3.Hash([]byte{0x00}, data[2]), blake3.Hash([]byte{0x00}, data[3]))
+	ok, err = merkletree.VerifyMProof(forged, merkletree.NewProof([][]byte{sibling}, 0), tree.MerkleRoot(), blake3, merkletree.WithRFC6962Prefixes())
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestDomainSeparationInvalidPrefixes(t *testing.T) {
+	data := [][]byte{[]byte("Foo")}
+
+	_, err := merkletree.NewTree(data, blake3, merkletree.WithDomainSeparation([]byte{0x00}, nil))
+	assert.EqualError(t, err, "domain separation requires both a leaf and a node prefix")
+
+	_, err = merkletree.NewTree(data, blake3, merkletree.WithDomainSeparation([]byte("leaf"), []byte("leaf-node")))
+	assert.EqualError(t, err, "leaf and node prefixes must not be a prefix of one another")
+
+	tree, err := merkletree.NewTree(data, blake3, merkletree.WithDomainSeparation([]byte("L"), []byte("N")))
+	assert.NoError(t, err)
+	assert.Equal(t, blake3.Hash([]byte("L"), data[0]), tree.MerkleRoot())
+}
+
+func TestDuplicateLeaves(t *testing.T) {
+	data := [][]byte{
+		[]byte("Foo"),
+		[]byte("Bar"),
+		[]byte("Foo"),
+		[]byte("Baz"),
+		[]byte("Foo"),
+	}
+
+	tree, err := merkletree.NewTree(data, blake3)
+	assert.NoError(t, err)
+
+	proof, err := tree.GenerateMProof([]byte("Foo"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), proof.Index)
+
+	proofs, err := tree.GenerateMProofs([]byte("Foo"))
+	assert.NoError(t, err)
+	assert.Len(t, proofs, 3)
+	for i, index := range []uint64{0, 2, 4} {
+		assert.Equal(t, index, proofs[i].Index)
+		ok, err := merkletree.VerifyMProof([]byte("Foo"), proofs[i], tree.MerkleRoot(), blake3)
+		assert.NoError(t, err)
+		assert.True(t, ok, fmt.Sprintf("failed to verify proof at index %d", index))
+	}
+
+	// Updating a duplicate keeps the lookup in sync.
+	assert.NoError(t, tree.UpdateLeaf(0, []byte("Qux")))
+	proofs, err = tree.GenerateMProofs([]byte("Foo"))
+	assert.NoError(t, err)
+	assert.Len(t, proofs, 2)
+	assert.Equal(t, uint64(2), proofs[0].Index)
+	assert.Equal(t, uint64(4), proofs[1].Index)
+
+	assert.NoError(t, tree.UpdateLeaf(3, []byte("Foo")))
+	proofs, err = tree.GenerateMProofs([]byte("Foo"))
+	assert.NoError(t, err)
+	assert.Len(t, proofs, 3)
+	assert.Equal(t, uint64(3), proofs[1].Index)
+
+	_, err = tree.GenerateMProofs([]byte("Baz"))
+	assert.EqualError(t, err, "data not found")
+}
+
+func TestGenerateMProofAt(t *testing.T) {
+	data := [][]byte{
+		[]byte("Foo"),
+		[]byte("Bar"),
+		[]byte("Baz"),
+	}
+
+	tree, err := merkletree.NewTree(data, blake3)
+	assert.NoError(t, err)
+
+	for i, d := range data {
+		proof, err := tree.GenerateMProofAt(uint64(i))
+		assert.NoError(t, err)
+		expected, err := tree.GenerateMProof(d)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, proof)
+	}
+
+	_, err = tree.GenerateMProofAt(3)
+	assert.EqualError(t, err, "index out of bounds")
+}
+
+func TestWithWorkers(t *testing.T) {
+	for _, n := range []int{1, 3, 1024, 1025, 5000} {
+		data := make([][]byte, n)
+		for i := range data {
+			data[i] = []byte(fmt.Sprintf("leaf-%d", i))
+		}
+
+		sequential, err := merkletree.NewTree(data, blake3)
+		assert.NoError(t, err)
+		concurrent, err := merkletree.NewTree(data, blake3, merkletree.WithWorkers(4))
+		assert.NoError(t, err)
+		assert.Equal(t, sequential.MerkleRoot(), concurrent.MerkleRoot(), fmt.Sprintf("unexpected root for %d leaves", n))
+
+		concurrent.Append(data...)
+		sequential.Append(data...)
+		assert.Equal(t, sequential.MerkleRoot(), concurrent.MerkleRoot(), fmt.Sprintf("unexpected root after append for %d leaves", n))
+	}
+}
+
+func TestStreamingHasher(t *testing.T) {
+	data := make([][]byte, 1000)
+	for i := range data {
+		data[i] = []byte(fmt.Sprintf("leaf-%d", i))
+	}
+
+	for i, name := range hash.Names() {
+		hashType, err := hash.ByName(name)
+		assert.NoError(t, err)
+		if _, ok := hashType.(hash.Streamer); !ok {
+			continue
+		}
+		for _, shape := range shapes {
+			opts := []merkletree.Option{merkletree.WithShape(shape), merkletree.WithWorkers(4)}
+			streaming, err := merkletree.NewTree(data, hashType, opts...)
+			assert.NoError(t, err)
+			plain, err := merkletree.NewTree(data, plainHash{hashType}, opts...)
+			assert.NoError(t, err)
+			assert.Equal(t, plain.MerkleRoot(), streaming.MerkleRoot(), fmt.Sprintf("unexpected %s root at test %d", shape, i))
+
+			assert.NoError(t, streaming.UpdateLeaf(7, []byte("updated")))
+			assert.NoError(t, plain.UpdateLeaf(7, []byte("updated")))
+			assert.Equal(t, plain.MerkleRoot(), streaming.MerkleRoot(), fmt.Sprintf("unexpected %s root after update at test %d", shape, i))
+
+			proof, err := streaming.GenerateMProof([]byte("leaf-999"))
+			assert.NoError(t, err)
+			verified, err := merkletree.VerifyMProof([]byte("leaf-999"), proof, streaming.MerkleRoot(), hashType, opts...)
+			assert.NoError(t, err)
+			assert.True(t, verified, fmt.Sprintf("failed to verify %s proof at test %d", shape, i))
+		}
+	}
+}
+
+func TestStreamingHasherAllocations(t *testing.T) {
+	data := make([][]byte, 1024)
+	for i := range data {
+		data[i] = []byte(fmt.Sprintf("leaf-%d", i))
+	}
+	newTree := func(hashType hash.HashType) func() {
+		return func() {
+			if _, err := merkletree.NewTree(data, hashType); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// Both trees allocate the lookup of leaves; only the plain one allocates every node on top of it.
+	streaming := testing.AllocsPerRun(10, newTree(blake3))
+	plain := testing.AllocsPerRun(10, newTree(plainHash{blake3}))
+	assert.Less(t, streaming, plain-2*float64(len(data)))
+}
+
+// batchCounter counts the batches hashed by a tree, hashing them one input at a time.
+type batchCounter struct {
+	hash.HashType
+	batches int64
+}
+
+func (h *batchCounter) HashBatch(dst []byte, inputs [][]byte) {
+	atomic.AddInt64(&h.batches, 1)
+	hash.HashBatch(h.HashType, dst, inputs)
+}
+
+func TestBatchHasher(t *testing.T) {
+	// Runs of leaves of the same length are hashed in batches, the other leaves one at a time.
+	data := make([][]byte, 1000)
+	for i := range data {
+		data[i] = []byte(fmt.Sprintf("leaf-%d", i))
+	}
+
+	tests := [][]merkletree.Option{
+		nil,
+		{merkletree.WithWorkers(4)},
+		{merkletree.WithRFC6962Prefixes()},
+		{merkletree.WithSortedPairs(), merkletree.WithWorkers(4)},
+	}
+	for i, opts := range tests {
+		for _, shape := range shapes {
+			opts := append([]merkletree.Option{merkletree.WithShape(shape)}, opts...)
+			counter := &batchCounter{HashType: plainHash{blake3}}
+			batched, err := merkletree.NewTree(data, counter, opts...)
+			assert.NoError(t, err)
+			assert.Greater(t, atomic.LoadInt64(&counter.batches), int64(0), fmt.Sprintf("no %s batch at test %d", shape, i))
+
+			plain, err := merkletree.NewTree(data, plainHash{blake3}, opts...)
+			assert.NoError(t, err)
+			assert.Equal(t, plain.MerkleRoot(), batched.MerkleRoot(), fmt.Sprintf("unexpected %s root at test %d", shape, i))
+
+			// BLAKE3 batches with SIMD instructions when the CPU supports them.
+			simd, err := merkletree.NewTree(data, blake3, opts...)
+			assert.NoError(t, err)
+			assert.Equal(t, plain.MerkleRoot(), simd.MerkleRoot(), fmt.Sprintf("unexpected %s BLAKE3 root at test %d", shape, i))
+		}
+	}
+}
+
+func TestKeyedTree(t *testing.T) {
+	data := [][]byte{[]byte("Foo"), []byte("Bar"), []byte("Baz")}
+	var key, otherKey [32]byte
+	copy(key[:], "tenant key of thirty-two bytes!!")
+	copy(otherKey[:], "another key of thirty-two bytes!")
+
+	tree, err := merkletree.NewTree(data, hash.NewBlake3Keyed(key))
+	assert.NoError(t, err)
+	unkeyed, err := merkletree.NewTree(data, blake3)
+	assert.NoError(t, err)
+	assert.NotEqual(t, unkeyed.MerkleRoot(), tree.MerkleRoot())
+
+	proof, err := tree.GenerateMProof([]byte("Bar"))
+	assert.NoError(t, err)
+	verified, err := merkletree.VerifyMProof([]byte("Bar"), proof, tree.MerkleRoot(), hash.NewBlake3Keyed(key))
+	assert.NoError(t, err)
+	assert.True(t, verified)
+	verified, err = merkletree.VerifyMProof([]byte("Bar"), proof, tree.MerkleRoot(), hash.NewBlake3Keyed(otherKey))
+	assert.NoError(t, err)
+	assert.False(t, verified)
+}
+
+func TestHashLength(t *testing.T) {
+	data := make([][]byte, 5)
+	for i := range data {
+		data[i] = []byte(fmt.Sprintf("leaf-%d", i))
+	}
+
+	for i, length := range []int{16, 20, 32, 64} {
+		hashType, err := hash.NewBlake3WithLength(length)
+		assert.NoError(t, err)
+		for _, shape := range shapes {
+			tree, err := merkletree.NewTree(data, hashType, merkletree.WithShape(shape))
+			assert.NoError(t, err)
+			assert.Len(t, tree.MerkleRoot(), length, fmt.Sprintf("unexpected %s root at test %d", shape, i))
+
+			proof, err := tree.GenerateMProof([]byte("leaf-4"))
+			assert.NoError(t, err)
+			for _, h := range proof.Hashes {
+				assert.Len(t, h, length, fmt.Sprintf("unexpected %s proof at test %d", shape, i))
+			}
+			verified, err := merkletree.VerifyMProof([]byte("leaf-4"), proof, tree.MerkleRoot(), hashType, merkletree.WithShape(shape))
+			assert.NoError(t, err)
+			assert.True(t, verified, fmt.Sprintf("failed to verify %s proof at test %d", shape, i))
+		}
+	}
+}
+
+func TestHashLengthMismatch(t *testing.T) {
+	data := [][]byte{[]byte("Foo"), []byte("Bar"), []byte("Baz")}
+	short, err := hash.NewBlake3WithLength(16)
+	assert.NoError(t, err)
+	tree, err := merkletree.NewTree(data, short)
+	assert.NoError(t, err)
+	proof, err := tree.GenerateMProof([]byte("Bar"))
+	assert.NoError(t, err)
+
+	// A proof from a tree with 16-byte digests checked with 32-byte ones.
+	_, err = merkletree.VerifyMProof([]byte("Bar"), proof, tree.MerkleRoot(), blake3)
+	assert.EqualError(t, err, "the proof was built with another hash length")
+	proof.Params = nil
+	_, err = merkletree.VerifyMProof([]byte("Bar"), proof, tree.MerkleRoot(), blake3)
+	assert.EqualError(t, err, "the root does not match the hash length")
+
+	// A proof mixing digest lengths.
+	full, err := merkletree.NewTree(data, blake3)
+	assert.NoError(t, err)
+	mixed, err := full.GenerateMProof([]byte("Bar"))
+	assert.NoError(t, err)
+	mixed.Hashes[0] = proof.Hashes[0]
+	_, err = merkletree.VerifyMProof([]byte("Bar"), mixed, full.MerkleRoot(), blake3)
+	assert.EqualError(t, err, "the proof hashes do not match the hash length")
+
+	multi, err := full.GenerateMultiProof([]uint64{0})
+	assert.NoError(t, err)
+	multi.Hashes[1] = multi.Hashes[1][:20]
+	_, err = merkletree.VerifyMultiProof([][]byte{[]byte("Foo")}, multi, full.MerkleRoot(), blake3)
+	assert.EqualError(t, err, "the proof hashes do not match the hash length")
+}
+
+func TestPoseidonTree(t *testing.T) {
+	// The leaves are field elements, as in a circuit.
+	data := make([][]byte, 3)
+	for i := range data {
+		data[i] = make([]byte, 32)
+		data[i][31] = byte(i + 1)
+	}
+	poseidon := hash.NewPoseidon()
+
+	tree, err := merkletree.NewTree(data, poseidon)
+	assert.NoError(t, err)
+	left := poseidon.Hash(poseidon.Hash(data[0]), poseidon.Hash(data[1]))
+	right := poseidon.Hash(poseidon.Hash(data[2]), make([]byte, 32))
+	assert.Equal(t, poseidon.Hash(left, right), tree.MerkleRoot())
+
+	for i, d := range data {
+		proof, err := tree.GenerateMProof(d)
+		assert.NoError(t, err)
+		verified, err := merkletree.VerifyMProof(d, proof, tree.MerkleRoot(), poseidon)
+		assert.NoError(t, err)
+		assert.True(t, verified, fmt.Sprintf("failed to verify proof for input %d", i))
+		verified, err = proof.Verify(d, tree.MerkleRoot())
+		assert.NoError(t, err)
+		assert.True(t, verified, fmt.Sprintf("failed to verify proof for input %d", i))
+	}
+}
+
+func TestPoseidonTreeInvalidInput(t *testing.T) {
+	poseidon := hash.NewPoseidon()
+	data := [][]byte{{1}, {2}, {3}}
+	tree, err := merkletree.NewTree(data, poseidon)
+	assert.NoError(t, err)
+	// A leaf longer than 32 bytes is not a field element, and is an error rather than a panic.
+	long := []byte("a leaf longer than thirty-two bytes")
+
+	_, err = merkletree.NewTree([][]byte{long}, poseidon)
+	assert.EqualError(t, err, "the poseidon input is not a field element")
+	// Domain separation hashes nodes of 3 inputs.
+	_, err = merkletree.NewTree(data, poseidon, merkletree.WithRFC6962Prefixes())
+	assert.EqualError(t, err, "poseidon hashes 1, 2, 4 or 5 field elements")
+	assert.EqualError(t, tree.UpdateLeaf(0, long), "the poseidon input is not a field element")
+	assert.EqualError(t, tree.UpdateLeaves(map[uint64][]byte{0: {4}, 1: long}), "the poseidon input is not a field element")
+	_, _, err = tree.InsertLeaf(0, long)
+	assert.EqualError(t, err, "the poseidon input is not a field element")
+	_, err = tree.GenerateMProof(long)
+	assert.EqualError(t, err, "the poseidon input is not a field element")
+
+	proof, err := tree.GenerateMProofAt(0)
+	assert.NoError(t, err)
+	_, err = merkletree.VerifyMProof(long, proof, tree.MerkleRoot(), poseidon)
+	assert.EqualError(t, err, "the poseidon input is not a field element")
+	_, err = merkletree.VerifyMultiProof([][]byte{long}, &merkletree.MultiProof{Indices: []uint64{0}, LeafCount: 3}, tree.MerkleRoot(), poseidon)
+	assert.EqualError(t, err, "the poseidon input is not a field element")
+
+	// A sibling past the order of the field can't be a Poseidon digest.
+	forged := *proof
+	forged.Hashes = [][]byte{bytes.Repeat([]byte{0xff}, 32), proof.Hashes[1]}
+	_, err = merkletree.VerifyMProof(data[0], &forged, tree.MerkleRoot(), poseidon)
+	assert.ErrorIs(t, err, merkletree.ErrMalformedProof)
+	_, err = merkletree.VerifyMProof(data[0], proof, bytes.Repeat([]byte{0xff}, 32), poseidon)
+	assert.ErrorIs(t, err, merkletree.ErrMalformedProof)
+}
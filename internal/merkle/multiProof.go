@@ -0,0 +1,172 @@
+package merkletree
+
+import (
+	"bytes"
+	"errors"
+	"github.com/reactivejson/merkleTree/internal/merkle/hash"
+	"math/bits"
+	"sort"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// MultiProof is a proof for several leaves of a Merkle tree at once.
+// Sibling hashes that can be computed from the proven leaves are left out, so it is much smaller than one
+// MerkleProof per leaf. As with OpenZeppelin's multiProofVerify the verifier rebuilds the root bottom-up,
+// consuming Hashes in order; here the position of each node, and so whether it is a left or right child
+// and whether its sibling is computed or taken from Hashes, follows from Indices and LeafCount.
+type MultiProof struct {
+	Hashes    [][]byte // sibling hashes that are not computed from the leaves, level by level from the leaves up, left to right
+	Indices   []uint64 // the indexes of the proven leaves, in ascending order
+	LeafCount uint64   // the number of leaves in the tree
+}
+
+// multiNode is a node of a level being rebuilt, identified by its index in the node layout.
+type multiNode struct {
+	index uint64
+	hash  []byte
+}
+
+// GenerateMultiProof generates a single proof for the leaves at the given indexes.
+// The indexes must be unique and within bounds; the proof lists them in ascending order, which is the order
+// the leaves have to be given to VerifyMultiProof.
+func (t *MerkleTree) GenerateMultiProof(indices []uint64) (*MultiProof, error) {
+	if len(indices) == 0 {
+		return nil, errors.New("no index to prove")
+	}
+	sorted := append([]uint64(nil), indices...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	for i, index := range sorted {
+		if index >= uint64(len(t.data)) {
+			return nil, errors.New("index out of bounds")
+		}
+		if i > 0 && sorted[i-1] == index {
+			return nil, errors.New("duplicate index")
+		}
+	}
+
+	width := uint64(len(t.nodes) / 2)
+	known := make([]uint64, len(sorted))
+	for i, index := range sorted {
+		known[i] = width + index
+	}
+
+	var hashes [][]byte
+	for known[0] > 1 {
+		parents := make([]uint64, 0, len(known))
+		for k := 0; k < len(known); k++ {
+			i := known[k]
+			if i%2 == 0 && k+1 < len(known) && known[k+1] == i+1 {
+				// Both children are known, the sibling is computed by the verifier.
+				k++
+			} else if t.nodes[i^1] != nil {
+				// A missing sibling is completed by the verifier from the leaf count.
+				hashes = append(hashes, t.nodes[i^1])
+			}
+			parents = append(parents, i/2)
+		}
+		known = parents
+	}
+
+	return &MultiProof{
+		Hashes:    hashes,
+		Indices:   sorted,
+		LeafCount: uint64(len(t.data)),
+	}, nil
+}
+
+// VerifyMultiProof verifies a multiproof for several pieces of input, given in the order of proof.Indices.
+// As with VerifyMProof only the root of the tree is needed, and the options must match the ones the tree was built with.
+//
+// This returns true if the proof is verified, otherwise false. An error is returned for a malformed proof.
+func VerifyMultiProof(leaves [][]byte, proof *MultiProof, root []byte, hashType hash.HashType, opts ...Option) (bool, error) {
+	if proof == nil {
+		return false, ErrNilProof
+	}
+	if hashType == nil {
+		return false, ErrNilHashType
+	}
+	cfg := newConfig(opts)
+	if err := cfg.validate(); err != nil {
+		return false, err
+	}
+	if len(leaves) == 0 || len(leaves) != len(proof.Indices) {
+		return false, errors.New("the number of leaves does not match the number of indices")
+	}
+	if err := checkLengths(hashType, root, proof.Hashes); err != nil {
+		return false, err
+	}
+	hasher := cfg.hasher(hashType)
+	if err := hasher.validate(leaves...); err != nil {
+		return false, err
+	}
+
+	width, err := paddedWidth(proof.LeafCount)
+	if err != nil {
+		return false, err
+	}
+
+	known := make([]multiNode, len(leaves))
+	for i, leaf := range leaves {
+		index := proof.Indices[i]
+		if index >= proof.LeafCount {
+			return false, errors.New("index out of bounds")
+		}
+		if i > 0 && proof.Indices[i-1] >= index {
+			return false, errors.New("indices must be unique and in ascending order")
+		}
+		known[i] = multiNode{index: width + index, hash: hasher.leaf(leaf)}
+	}
+
+	// level is the index of the first node of the current level, and count the number of nodes in that level.
+	level, count := width, width
+	if cfg.shape != ShapeZeroPad {
+		count = proof.LeafCount
+	}
+
+	hashes := proof.Hashes
+	for ; known[0].index > 1; level, count = level/2, (count+1)/2 {
+		parents := make([]multiNode, 0, len(known))
+		for k := 0; k < len(known); k++ {
+			node := known[k]
+			var parent []byte
+			switch {
+			case node.index%2 == 0 && k+1 < len(known) && known[k+1].index == node.index+1:
+				parent = hasher.node(node.hash, known[k+1].hash)
+				k++
+			case (node.index^1)-level >= count:
+				parent = hasher.branch(node.hash, nil)
+			case len(hashes) == 0:
+				return false, errors.New("not enough hashes in the proof")
+			case node.index%2 == 0:
+				parent = hasher.node(node.hash, hashes[0])
+				hashes = hashes[1:]
+			default:
+				parent = hasher.node(hashes[0], node.hash)
+				hashes = hashes[1:]
+			}
+			parents = append(parents, multiNode{index: node.index / 2, hash: parent})
+		}
+		known = parents
+	}
+	if len(hashes) != 0 {
+		return false, errors.New("too many hashes in the proof")
+	}
+
+	return bytes.Equal(root, known[0].hash), nil
+}
+
+// paddedWidth returns the number of leaves of a tree of the leaf count once padded to a power of 2. A leaf count
+// taken from a proof can be anything, and one past 1<<63 has no width that fits a uint64.
+func paddedWidth(leafCount uint64) (uint64, error) {
+	if leafCount > 1<<63 {
+		return 0, errLeafCountTooLarge
+	}
+	if leafCount <= 1 {
+		return 1, nil
+	}
+	return 1 << uint(bits.Len64(leafCount-1)), nil
+}
@@ -0,0 +1,126 @@
+package merkletree_test
+
+import (
+	"fmt"
+	merkletree "github.com/reactivejson/merkleTree/internal/merkle"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+func TestMultiProof(t *testing.T) {
+	tests := []struct {
+		leaves  int
+		indices []uint64
+	}{
+		{leaves: 1, indices: []uint64{0}},
+		{leaves: 2, indices: []uint64{0, 1}},
+		{leaves: 5, indices: []uint64{4}},
+		{leaves: 5, indices: []uint64{3, 0, 4}},
+		{leaves: 8, indices: []uint64{0, 1, 2, 3, 4, 5, 6, 7}},
+		{leaves: 13, indices: []uint64{12, 1, 6, 7}},
+	}
+
+	for i, test := range tests {
+		data := leaves(test.leaves)
+		tree, err := merkletree.NewTree(data, blake3, merkletree.WithRFC6962Prefixes())
+		assert.NoError(t, err)
+
+		proof, err := tree.GenerateMultiProof(test.indices)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(test.leaves), proof.LeafCount)
+
+		proven := make([][]byte, len(proof.Indices))
+		for j, index := range proof.Indices {
+			proven[j] = data[index]
+		}
+		ok, err := merkletree.VerifyMultiProof(proven, proof, tree.MerkleRoot(), blake3, merkletree.WithRFC6962Prefixes())
+		assert.NoError(t, err)
+		assert.True(t, ok, fmt.Sprintf("failed to verify proof at test %d", i))
+
+		// A tampered leaf is rejected.
+		proven[0] = []byte("tampered")
+		ok, err = merkletree.VerifyMultiProof(proven, proof, tree.MerkleRoot(), blake3, merkletree.WithRFC6962Prefixes())
+		assert.NoError(t, err)
+		assert.False(t, ok, fmt.Sprintf("verified a tampered proof at test %d", i))
+	}
+}
+
+func TestMultiProofSize(t *testing.T) {
+	data := leaves(1024)
+	tree, err := merkletree.NewTree(data, blake3)
+	assert.NoError(t, err)
+
+	indices := make([]uint64, 500)
+	for i := range indices {
+		indices[i] = uint64(i * 2)
+	}
+
+	proof, err := tree.GenerateMultiProof(indices)
+	assert.NoError(t, err)
+
+	single := 0
+	for _, index := range indices {
+		p, err := tree.GenerateMProofAt(index)
+		assert.NoError(t, err)
+		single += len(p.Hashes)
+	}
+
+	// 500 siblings at the leaf level, then one at each of the two levels where the known nodes are odd in number.
+	assert.Equal(t, 5000, single)
+	assert.Equal(t, 502, len(proof.Hashes))
+
+	proven := make([][]byte, len(indices))
+	for i, index := range indices {
+		proven[i] = data[index]
+	}
+	ok, err := merkletree.VerifyMultiProof(proven, proof, tree.MerkleRoot(), blake3)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestMultiProofErrors(t *testing.T) {
+	tree, err := merkletree.NewTree(leaves(5), blake3)
+	assert.NoError(t, err)
+
+	_, err = tree.GenerateMultiProof(nil)
+	assert.EqualError(t, err, "no index to prove")
+	_, err = tree.GenerateMultiProof([]uint64{1, 5})
+	assert.EqualError(t, err, "index out of bounds")
+	_, err = tree.GenerateMultiProof([]uint64{1, 1})
+	assert.EqualError(t, err, "duplicate index")
+
+	proof, err := tree.GenerateMultiProof([]uint64{1, 3})
+	assert.NoError(t, err)
+	proven := [][]byte{[]byte("leaf-1"), []byte("leaf-3")}
+
+	_, err = merkletree.VerifyMultiProof(proven[:1], proof, tree.MerkleRoot(), blake3)
+	assert.EqualError(t, err, "the number of leaves does not match the number of indices")
+
+	short := *proof
+	short.Hashes = proof.Hashes[1:]
+	_, err = merkletree.VerifyMultiProof(proven, &short, tree.MerkleRoot(), blake3)
+	assert.EqualError(t, err, "not enough hashes in the proof")
+
+	long := *proof
+	long.Hashes = append(append([][]byte(nil), proof.Hashes...), proof.Hashes[0])
+	_, err = merkletree.VerifyMultiProof(proven, &long, tree.MerkleRoot(), blake3)
+	assert.EqualError(t, err, "too many hashes in the proof")
+
+	unordered := *proof
+	unordered.Indices = []uint64{3, 1}
+	_, err = merkletree.VerifyMultiProof(proven, &unordered, tree.MerkleRoot(), blake3)
+	assert.EqualError(t, err, "indices must be unique and in ascending order")
+
+	// A leaf count past 1<<63 has no padded width, and must not make the verifier loop forever.
+	huge := *proof
+	huge.LeafCount = 1<<63 + 1
+	_, err = merkletree.VerifyMultiProof(proven, &huge, tree.MerkleRoot(), blake3)
+	assert.EqualError(t, err, "the proof leaf count is too large")
+	assert.ErrorIs(t, err, merkletree.ErrMalformedProof)
+}
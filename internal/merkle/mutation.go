@@ -0,0 +1,230 @@
+package merkletree
+
+import (
+	"errors"
+	"sort"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// Append adds leaves to the end of the tree without rebuilding it.
+// When the padded width of the tree is exceeded the node layout is doubled (as many times as needed),
+// the existing nodes becoming the left part of the new tree. Only the nodes on the paths from the
+// appended leaves to the root are rehashed.
+//
+// Proofs generated before the append stay valid against the root they were generated for. A proof
+// against the new root has to be generated again: every path to the root passes through a node that
+// covers one of the appended leaves, so at least one sibling hash changes.
+//
+// Append can't return an error: with a hash type that can't hash every input, such as Poseidon, the leaves must be
+// checked with hash.Validate first, or Append panics.
+func (t *MerkleTree) Append(leaves ...[]byte) {
+	if len(leaves) == 0 {
+		return
+	}
+
+	from := len(t.data)
+	to := from + len(leaves)
+
+	// Double the layout until the new leaves fit in it.
+	width := len(t.nodes) / 2
+	for width < to {
+		width *= 2
+	}
+	t.resize(width)
+
+	hasher := t.hasher()
+	for i, leaf := range leaves {
+		t.nodes[width+from+i] = hasher.leaf(leaf)
+		t.addIndex(t.nodes[width+from+i], uint64(from+i))
+	}
+	t.data = append(t.data, leaves...)
+
+	t.rehash(from, to)
+}
+
+// InsertLeaf inserts a leaf at the given index, shifting the leaves at and after it one position to the right.
+// index may be equal to the number of leaves, in which case the leaf is appended.
+// The padded width of the tree is doubled when the leaf count crosses a power of 2, and only the branches
+// above the shifted leaves are rehashed. It returns the root before and after the insertion.
+func (t *MerkleTree) InsertLeaf(index uint64, data []byte) (oldRoot []byte, newRoot []byte, err error) {
+	if index > uint64(len(t.data)) {
+		return nil, nil, errors.New("index out of bounds")
+	}
+	if err := t.hasher().validate(data); err != nil {
+		return nil, nil, err
+	}
+	oldRoot = t.MerkleRoot()
+	n := len(t.data) + 1
+
+	width := len(t.nodes) / 2
+	for width < n {
+		width *= 2
+	}
+	t.resize(width)
+
+	// Shift the leaves after the index to the right, and hash the new one in its place.
+	leaves := t.nodes[width : width+n]
+	copy(leaves[index+1:], leaves[index:n-1])
+	leaves[index] = t.hasher().leaf(data)
+
+	newData := make([][]byte, 0, n)
+	newData = append(newData, t.data[:index]...)
+	newData = append(newData, data)
+	t.data = append(newData, t.data[index:]...)
+
+	t.indexLeaves()
+	t.rehash(int(index), n)
+	return oldRoot, t.MerkleRoot(), nil
+}
+
+// RemoveLeaf removes the leaf at the given index, shifting the leaves after it one position to the left.
+// The last leaf slot becomes padding, and the padded width of the tree is halved when the leaf count drops
+// to a power of 2. Only the branches above the shifted leaves are rehashed.
+// It returns the root before and after the removal. A tree always keeps at least one leaf.
+func (t *MerkleTree) RemoveLeaf(index uint64) (oldRoot []byte, newRoot []byte, err error) {
+	if index >= uint64(len(t.data)) {
+		return nil, nil, errors.New("index out of bounds")
+	}
+	if len(t.data) == 1 {
+		return nil, nil, errors.New("the merkle tree should contains at least 1 piece of input")
+	}
+	oldRoot = t.MerkleRoot()
+	n := len(t.data)
+
+	// Shift the leaves after the index to the left, the last slot becoming padding (or empty, with the other shapes).
+	width := len(t.nodes) / 2
+	leaves := t.nodes[width : width+n]
+	copy(leaves[index:], leaves[index+1:])
+	leaves[n-1] = nil
+	if t.shape == ShapeZeroPad {
+		leaves[n-1] = make([]byte, t.hash.HashLength())
+	}
+
+	newData := make([][]byte, 0, n-1)
+	newData = append(newData, t.data[:index]...)
+	t.data = append(newData, t.data[index+1:]...)
+
+	// Halve the layout while the remaining leaves fit in its left half.
+	for width > 1 && width/2 >= n-1 {
+		width /= 2
+	}
+	t.resize(width)
+
+	t.indexLeaves()
+	t.rehash(int(index), min(n, width))
+	return oldRoot, t.MerkleRoot(), nil
+}
+
+// UpdateLeaves updates several leaves at once. All the leaves are written first, then every branch above them
+// is rehashed exactly once, level by level, so leaves sharing ancestors don't rehash them over and over.
+// If any index is out of bounds, or any input can't be hashed, an error is returned and the tree is left untouched.
+func (t *MerkleTree) UpdateLeaves(updates map[uint64][]byte) error {
+	hasher := t.hasher()
+	indexes := make([]uint64, 0, len(updates))
+	for index, data := range updates {
+		if index >= uint64(len(t.data)) {
+			return errors.New("index out of bounds")
+		}
+		if err := hasher.validate(data); err != nil {
+			return err
+		}
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+
+	width := uint64(len(t.nodes) / 2)
+
+	// dirty holds the node indexes of the current level that have to be rehashed, in ascending order.
+	dirty := make([]uint64, len(indexes))
+	for i, index := range indexes {
+		newLeaf := hasher.leaf(updates[index])
+		t.data[index] = updates[index]
+		t.removeIndex(t.nodes[width+index], index)
+		t.addIndex(newLeaf, index)
+		t.nodes[width+index] = newLeaf
+		dirty[i] = width + index
+	}
+
+	for len(dirty) > 0 && dirty[0] > 1 {
+		// Parents of sorted nodes are sorted, so duplicates are next to each other.
+		parents := dirty[:0]
+		for _, nodeIndex := range dirty {
+			if len(parents) == 0 || parents[len(parents)-1] != nodeIndex/2 {
+				parents = append(parents, nodeIndex/2)
+			}
+		}
+		dirty = parents
+
+		parallel(len(dirty), t.workers, func(from, to int) {
+			for _, i := range dirty[from:to] {
+				t.nodes[i] = hasher.branch(t.nodes[2*i], t.nodes[2*i+1])
+			}
+		})
+	}
+
+	return nil
+}
+
+// resize moves the nodes of the tree into a layout with the given padded width (a power of 2).
+// At each level the nodes that still fit are kept in place, the others are dropped, and the new
+// slots are filled with the padding hash of that level, or left empty unless the shape is ShapeZeroPad.
+// The caller is responsible for rehashing the nodes whose children changed.
+func (t *MerkleTree) resize(width int) {
+	oldWidth := len(t.nodes) / 2
+	if width == oldWidth {
+		return
+	}
+
+	hasher := t.hasher()
+	nodes := make([][]byte, 2*width)
+	var pad []byte
+	if t.shape == ShapeZeroPad {
+		pad = make([]byte, t.hash.HashLength())
+	}
+
+	// Walk the levels from the leaves up to the root; at each level there are w nodes starting at index w.
+	for w, ow := width, oldWidth; w >= 1; w, ow = w/2, ow/2 {
+		kept := 0
+		if ow >= 1 {
+			kept = copy(nodes[w:w+min(w, ow)], t.nodes[ow:2*ow])
+		}
+		for i := w + kept; i < 2*w; i++ {
+			nodes[i] = pad
+		}
+		pad = hasher.branch(pad, pad)
+	}
+
+	t.nodes = nodes
+}
+
+// rehash recomputes the branches above the leaves in [from, to), level by level, hashing each of them once.
+func (t *MerkleTree) rehash(from, to int) {
+	if from >= to {
+		return
+	}
+	hasher := t.hasher()
+	width := len(t.nodes) / 2
+
+	// lo and hi are the first and last dirty node indexes of the current level.
+	lo, hi := width+from, width+to-1
+	for lo > 1 {
+		lo, hi = lo/2, hi/2
+		first := lo
+		parallel(hi-lo+1, t.workers, func(from, to int) {
+			for i := first + from; i < first+to; i++ {
+				t.nodes[i] = hasher.branch(t.nodes[2*i], t.nodes[2*i+1])
+			}
+		})
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,211 @@
+package merkletree_test
+
+import (
+	"fmt"
+	merkletree "github.com/reactivejson/merkleTree/internal/merkle"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// leaves generates n distinct pieces of input.
+func leaves(n int) [][]byte {
+	data := make([][]byte, n)
+	for i := range data {
+		data[i] = []byte(fmt.Sprintf("leaf-%d", i))
+	}
+	return data
+}
+
+func TestAppend(t *testing.T) {
+	tests := []struct {
+		initial int
+		appends []int
+	}{
+		{initial: 1, appends: []int{1}},
+		{initial: 1, appends: []int{1, 1, 1, 1}},
+		{initial: 3, appends: []int{2}},
+		{initial: 4, appends: []int{1}},
+		{initial: 5, appends: []int{12}},
+		{initial: 6, appends: []int{2, 9, 1}},
+	}
+
+	for i, test := range tests {
+		all := leaves(test.initial)
+		tree, err := merkletree.NewTree(leaves(test.initial), blake3, merkletree.WithRFC6962Prefixes())
+		assert.NoError(t, err)
+
+		for _, n := range test.appends {
+			extra := leaves(len(all) + n)[len(all):]
+			all = append(all, extra...)
+			tree.Append(extra...)
+
+			expected, err := merkletree.NewTree(all, blake3, merkletree.WithRFC6962Prefixes())
+			assert.NoError(t, err)
+			assert.Equal(t, expected.MerkleRoot(), tree.MerkleRoot(), fmt.Sprintf("unexpected root at test %d size %d", i, len(all)))
+		}
+
+		for j, data := range all {
+			proof, err := tree.GenerateMProof(data)
+			assert.NoError(t, err)
+			ok, err := merkletree.VerifyMProof(data, proof, tree.MerkleRoot(), blake3, merkletree.WithRFC6962Prefixes())
+			assert.NoError(t, err)
+			assert.True(t, ok, fmt.Sprintf("failed to verify proof at test %d input %d", i, j))
+		}
+	}
+}
+
+func TestAppendOldProofs(t *testing.T) {
+	tree, err := merkletree.NewTree(leaves(3), blake3)
+	assert.NoError(t, err)
+	oldRoot := tree.MerkleRoot()
+	proof, err := tree.GenerateMProof([]byte("leaf-0"))
+	assert.NoError(t, err)
+
+	tree.Append([]byte("leaf-3"), []byte("leaf-4"))
+
+	// The old proof is still valid against the old root, but not against the new one.
+	ok, err := merkletree.VerifyMProof([]byte("leaf-0"), proof, oldRoot, blake3)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = merkletree.VerifyMProof([]byte("leaf-0"), proof, tree.MerkleRoot(), blake3)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestInsertLeaf(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		for index := 0; index <= n; index++ {
+			tree, err := merkletree.NewTree(leaves(n), blake3)
+			assert.NoError(t, err)
+			before := tree.MerkleRoot()
+
+			oldRoot, newRoot, err := tree.InsertLeaf(uint64(index), []byte("inserted"))
+			assert.NoError(t, err)
+
+			expectedData := append(append(leaves(n)[:index:index], []byte("inserted")), leaves(n)[index:]...)
+			expected, err := merkletree.NewTree(expectedData, blake3)
+			assert.NoError(t, err)
+			assert.Equal(t, before, oldRoot)
+			assert.Equal(t, expected.MerkleRoot(), newRoot, fmt.Sprintf("unexpected root inserting at %d of %d", index, n))
+			assert.Equal(t, expected.MerkleRoot(), tree.MerkleRoot())
+
+			proof, err := tree.GenerateMProof([]byte("inserted"))
+			assert.NoError(t, err)
+			assert.Equal(t, uint64(index), proof.Index)
+			ok, err := merkletree.VerifyMProof([]byte("inserted"), proof, tree.MerkleRoot(), blake3)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+		}
+	}
+
+	tree, err := merkletree.NewTree(leaves(3), blake3)
+	assert.NoError(t, err)
+	_, _, err = tree.InsertLeaf(4, []byte("inserted"))
+	assert.EqualError(t, err, "index out of bounds")
+}
+
+func TestRemoveLeaf(t *testing.T) {
+	for n := 2; n <= 9; n++ {
+		for index := 0; index < n; index++ {
+			tree, err := merkletree.NewTree(leaves(n), blake3)
+			assert.NoError(t, err)
+			before := tree.MerkleRoot()
+
+			oldRoot, newRoot, err := tree.RemoveLeaf(uint64(index))
+			assert.NoError(t, err)
+
+			expectedData := append(leaves(n)[:index:index], leaves(n)[index+1:]...)
+			expected, err := merkletree.NewTree(expectedData, blake3)
+			assert.NoError(t, err)
+			assert.Equal(t, before, oldRoot)
+			assert.Equal(t, expected.MerkleRoot(), newRoot, fmt.Sprintf("unexpected root removing %d of %d", index, n))
+
+			for j, data := range expectedData {
+				proof, err := tree.GenerateMProof(data)
+				assert.NoError(t, err)
+				assert.Equal(t, uint64(j), proof.Index)
+				ok, err := merkletree.VerifyMProof(data, proof, tree.MerkleRoot(), blake3)
+				assert.NoError(t, err)
+				assert.True(t, ok, fmt.Sprintf("failed to verify proof for input %d removing %d of %d", j, index, n))
+			}
+		}
+	}
+
+	tree, err := merkletree.NewTree(leaves(1), blake3)
+	assert.NoError(t, err)
+	_, _, err = tree.RemoveLeaf(1)
+	assert.EqualError(t, err, "index out of bounds")
+	_, _, err = tree.RemoveLeaf(0)
+	assert.EqualError(t, err, "the merkle tree should contains at least 1 piece of input")
+}
+
+func TestMutationsKeepLookup(t *testing.T) {
+	tree, err := merkletree.NewTree(leaves(3), blake3)
+	assert.NoError(t, err)
+
+	tree.Append([]byte("leaf-1"))
+	_, _, err = tree.InsertLeaf(0, []byte("leaf-2"))
+	assert.NoError(t, err)
+	_, _, err = tree.RemoveLeaf(2)
+	assert.NoError(t, err)
+
+	// leaf-2, leaf-0, leaf-2, leaf-1
+	proofs, err := tree.GenerateMProofs([]byte("leaf-2"))
+	assert.NoError(t, err)
+	assert.Len(t, proofs, 2)
+	assert.Equal(t, uint64(0), proofs[0].Index)
+	assert.Equal(t, uint64(2), proofs[1].Index)
+
+	proof, err := tree.GenerateMProof([]byte("leaf-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), proof.Index)
+}
+
+func TestUpdateLeaves(t *testing.T) {
+	for _, n := range []int{1, 2, 5, 8, 100} {
+		batched, err := merkletree.NewTree(leaves(n), blake3, merkletree.WithWorkers(2))
+		assert.NoError(t, err)
+		sequential, err := merkletree.NewTree(leaves(n), blake3)
+		assert.NoError(t, err)
+
+		updates := make(map[uint64][]byte)
+		for i := 0; i < n; i += 3 {
+			updates[uint64(i)] = []byte(fmt.Sprintf("updated-%d", i))
+			assert.NoError(t, sequential.UpdateLeaf(uint64(i), updates[uint64(i)]))
+		}
+
+		assert.NoError(t, batched.UpdateLeaves(updates))
+		assert.Equal(t, sequential.MerkleRoot(), batched.MerkleRoot(), fmt.Sprintf("unexpected root for %d leaves", n))
+
+		proof, err := batched.GenerateMProof([]byte("updated-0"))
+		assert.NoError(t, err)
+		ok, err := merkletree.VerifyMProof([]byte("updated-0"), proof, batched.MerkleRoot(), blake3)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	}
+}
+
+func TestUpdateLeavesOutOfBounds(t *testing.T) {
+	tree, err := merkletree.NewTree(leaves(5), blake3)
+	assert.NoError(t, err)
+	root := tree.MerkleRoot()
+
+	err = tree.UpdateLeaves(map[uint64][]byte{
+		0: []byte("updated-0"),
+		5: []byte("updated-5"),
+	})
+	assert.EqualError(t, err, "index out of bounds")
+
+	// Nothing was written.
+	assert.Equal(t, root, tree.MerkleRoot())
+	_, err = tree.GenerateMProof([]byte("updated-0"))
+	assert.EqualError(t, err, "data not found")
+	_, err = tree.GenerateMProof([]byte("leaf-0"))
+	assert.NoError(t, err)
+}
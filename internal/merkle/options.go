@@ -0,0 +1,159 @@
+package merkletree
+
+import (
+	"bytes"
+	"errors"
+	hash2 "github.com/reactivejson/merkleTree/internal/merkle/hash"
+	"runtime"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// Option configures how a Merkle tree is built, and how proofs generated from it are verified.
+type Option func(*config)
+
+// config holds the settings collected from the options passed to NewTree or VerifyMProof.
+type config struct {
+	// domain holds the prefixes used to separate leaf hashes from interior node hashes.
+	domain DomainSeparation
+	// workers is the number of goroutines hashing the nodes of a level; 0 or 1 hashes them sequentially.
+	workers int
+	// shape is how a level with an odd number of nodes is completed.
+	shape Shape
+	// sortedPairs tells whether the children of a node are sorted before they are hashed.
+	sortedPairs bool
+}
+
+// newConfig applies the options on top of the default settings.
+func newConfig(opts []Option) config {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.shape == ShapeRFC6962 && !cfg.domain.Enabled() {
+		cfg.domain = DomainSeparation{LeafPrefix: []byte{0x00}, NodePrefix: []byte{0x01}}
+	}
+	return cfg
+}
+
+// validate checks that the settings are consistent.
+func (c config) validate() error {
+	if c.shape < ShapeZeroPad || c.shape > ShapeRFC6962 {
+		return errors.New("unknown tree shape")
+	}
+	return c.domain.validate()
+}
+
+// hasher returns the leaf and node hasher for the hash type with these settings.
+func (c config) hasher(hash hash2.HashType) treeHasher {
+	return newTreeHasher(hash, c.domain, c.shape, c.sortedPairs)
+}
+
+// Shape is how a level of the tree with an odd number of nodes is completed.
+type Shape int
+
+const (
+	// ShapeZeroPad pads the leaves with zero-filled hashes up to the next power of 2. This is the default.
+	ShapeZeroPad Shape = iota
+	// ShapeDuplicateLast pairs the last node of an odd level with itself, as Bitcoin does.
+	ShapeDuplicateLast
+	// ShapePromoteOdd moves the last node of an odd level up unchanged.
+	ShapePromoteOdd
+	// ShapeRFC6962 splits n leaves at the largest power of 2 smaller than n, recursively, as RFC 6962 does.
+	// This gives the same tree as ShapePromoteOdd, but tags leaves and nodes with the RFC 6962 prefixes unless
+	// other prefixes are given with WithDomainSeparation.
+	ShapeRFC6962
+)
+
+// String returns the name of the shape.
+func (s Shape) String() string {
+	switch s {
+	case ShapeZeroPad:
+		return "zero-pad"
+	case ShapeDuplicateLast:
+		return "duplicate-last"
+	case ShapePromoteOdd:
+		return "promote-odd"
+	case ShapeRFC6962:
+		return "rfc6962"
+	default:
+		return "unknown"
+	}
+}
+
+// WithShape sets how a level with an odd number of nodes is completed; see Shape.
+func WithShape(shape Shape) Option {
+	return func(c *config) {
+		c.shape = shape
+	}
+}
+
+// WithSortedPairs sorts the two children of every node before hashing them, as OpenZeppelin's MerkleProof does, which
+// makes node hashing commutative: a proof is then verified without knowing on which side each sibling is, and with
+// Keccak-256 the proofs of a tree verify with MerkleProof.verify on-chain.
+func WithSortedPairs() Option {
+	return func(c *config) {
+		c.sortedPairs = true
+	}
+}
+
+// DomainSeparation holds the tags prepended to the hash input of leaves and interior nodes.
+// Without it a leaf whose input is the concatenation of two child hashes has the same digest as
+// the interior node above those children, which allows a forged, shorter proof to be verified.
+// The zero value disables domain separation and keeps the original hashing scheme.
+type DomainSeparation struct {
+	// LeafPrefix is prepended to the input of every leaf before it is hashed.
+	LeafPrefix []byte
+	// NodePrefix is prepended to the concatenated children of every interior node before it is hashed.
+	NodePrefix []byte
+}
+
+// Enabled reports whether leaf and node hashes are tagged.
+func (d DomainSeparation) Enabled() bool {
+	return len(d.LeafPrefix) > 0 || len(d.NodePrefix) > 0
+}
+
+// validate checks that the two tags can never produce the same preimage.
+// This requires both tags to be set and neither to be a prefix of the other.
+func (d DomainSeparation) validate() error {
+	if !d.Enabled() {
+		return nil
+	}
+	if len(d.LeafPrefix) == 0 || len(d.NodePrefix) == 0 {
+		return errors.New("domain separation requires both a leaf and a node prefix")
+	}
+	if bytes.HasPrefix(d.LeafPrefix, d.NodePrefix) || bytes.HasPrefix(d.NodePrefix, d.LeafPrefix) {
+		return errors.New("leaf and node prefixes must not be a prefix of one another")
+	}
+	return nil
+}
+
+// WithRFC6962Prefixes tags leaves with 0x00 and interior nodes with 0x01, as described in RFC 6962.
+func WithRFC6962Prefixes() Option {
+	return WithDomainSeparation([]byte{0x00}, []byte{0x01})
+}
+
+// WithDomainSeparation tags leaves and interior nodes with caller-supplied prefixes.
+// Both prefixes must be non-empty and neither can be a prefix of the other.
+func WithDomainSeparation(leafPrefix, nodePrefix []byte) Option {
+	return func(c *config) {
+		c.domain = DomainSeparation{
+			LeafPrefix: append([]byte(nil), leafPrefix...),
+			NodePrefix: append([]byte(nil), nodePrefix...),
+		}
+	}
+}
+
+// WithWorkers hashes the leaves and each level of branches concurrently, split in chunks across n goroutines.
+// If n is 0 or less one goroutine per CPU is used. The root is identical to the one built sequentially.
+func WithWorkers(n int) Option {
+	return func(c *config) {
+		if n <= 0 {
+			n = runtime.GOMAXPROCS(0)
+		}
+		c.workers = n
+	}
+}
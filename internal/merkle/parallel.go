@@ -0,0 +1,36 @@
+package merkletree
+
+import "sync"
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// minChunk is the smallest number of nodes handed to a goroutine; below it the scheduling cost outweighs the hashing.
+const minChunk = 1024
+
+// parallel calls fn over [0, n) split in contiguous chunks, running the chunks on up to workers goroutines.
+// It returns once every chunk is done. With a single worker, or too little work, fn is called once on the caller's goroutine.
+func parallel(n, workers int, fn func(from, to int)) {
+	if workers <= 1 || n <= minChunk {
+		fn(0, n)
+		return
+	}
+
+	chunk := (n + workers - 1) / workers
+	if chunk < minChunk {
+		chunk = minChunk
+	}
+
+	var wg sync.WaitGroup
+	for from := 0; from < n; from += chunk {
+		to := min(from+chunk, n)
+		wg.Add(1)
+		go func(from, to int) {
+			defer wg.Done()
+			fn(from, to)
+		}(from, to)
+	}
+	wg.Wait()
+}
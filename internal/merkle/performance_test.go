@@ -40,3 +40,82 @@ func BenchmarkMerkleTree1000(b *testing.B)    { benchmarkMerkleTree(1000, b) }
 func BenchmarkMerkleTree10000(b *testing.B)   { benchmarkMerkleTree(10000, b) }
 func BenchmarkMerkleTree100000(b *testing.B)  { benchmarkMerkleTree(100000, b) }
 func BenchmarkMerkleTree1000000(b *testing.B) { benchmarkMerkleTree(1000000, b) }
+
+func benchmarkNewTree(n int, workers int, b *testing.B) {
+	data := make([][]byte, n)
+	for i := 0; i < n; i++ {
+		data[i] = make([]byte, 32)
+		rand.Read(data[i])
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := merkletree.NewTree(data, hash.NewBlake3(), merkletree.WithWorkers(workers)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNewTree100000(b *testing.B)          { benchmarkNewTree(100000, 1, b) }
+func BenchmarkNewTree100000Parallel(b *testing.B)  { benchmarkNewTree(100000, 0, b) }
+func BenchmarkNewTree1000000(b *testing.B)         { benchmarkNewTree(1000000, 1, b) }
+func BenchmarkNewTree1000000Parallel(b *testing.B) { benchmarkNewTree(1000000, 0, b) }
+
+// plainHash hides the hash.Streamer implementation of a hash type, so that every digest is allocated by Hash.
+type plainHash struct{ hash.HashType }
+
+func benchmarkNewTreeAllocs(n int, hashType hash.HashType, b *testing.B) {
+	data := make([][]byte, n)
+	for i := 0; i < n; i++ {
+		data[i] = make([]byte, 32)
+		rand.Read(data[i])
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := merkletree.NewTree(data, hashType); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNewTree100000Streaming(b *testing.B) {
+	benchmarkNewTreeAllocs(100000, hash.NewBlake3(), b)
+}
+func BenchmarkNewTree100000Plain(b *testing.B) {
+	benchmarkNewTreeAllocs(100000, plainHash{hash.NewBlake3()}, b)
+}
+
+// streamingHash hides the hash.BatchHasher implementation of a hash type, so that every node is hashed on its own.
+type streamingHash struct{ hash.Streamer }
+
+func BenchmarkNewTree100000Unbatched(b *testing.B) {
+	benchmarkNewTreeAllocs(100000, streamingHash{hash.NewBlake3()}, b)
+}
+
+func benchmarkVerifyMProofAllocs(hashType hash.HashType, b *testing.B) {
+	data := make([][]byte, 100000)
+	for i := range data {
+		data[i] = make([]byte, 32)
+		rand.Read(data[i])
+	}
+	tree, err := merkletree.NewTree(data, hashType)
+	if err != nil {
+		b.Fatal(err)
+	}
+	proof, err := tree.GenerateMProofAt(12345)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := merkletree.VerifyMProof(data[12345], proof, tree.MerkleRoot(), hashType); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkVerifyMProofStreaming(b *testing.B) { benchmarkVerifyMProofAllocs(hash.NewBlake3(), b) }
+func BenchmarkVerifyMProofPlain(b *testing.B) {
+	benchmarkVerifyMProofAllocs(plainHash{hash.NewBlake3()}, b)
+}
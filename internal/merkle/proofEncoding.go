@@ -0,0 +1,300 @@
+package merkletree
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// proofVersion is the version of the binary encoding of proofs, the first byte of every encoded proof.
+const proofVersion = 1
+
+// The flags of an encoded proof.
+const (
+	proofHasParams   = 1 << 0
+	proofSortedPairs = 1 << 1
+	proofHasRoot     = 1 << 2
+	proofHasLeaf     = 1 << 3
+	proofHasPadding  = 1 << 4
+)
+
+const (
+	// maxProofDepth is the largest number of hashes in a proof: a tree indexed by uint64 has at most 64 levels.
+	maxProofDepth = 64
+	// maxProofFieldLength bounds the hash length, algorithm name and domain prefixes of an encoded proof.
+	maxProofFieldLength = 255
+)
+
+// MarshalBinary encodes the proof as:
+//   - the version byte, 1, and a byte of flags: whether the proof carries its tree parameters, whether the tree
+//     sorts its pairs, and whether the proof carries its root and its leaf hash;
+//   - the index, the leaf count, the hash length and the number of hashes as unsigned varints, followed by the hashes,
+//     the root and the leaf hash;
+//   - if the proof carries its tree parameters, the shape as a varint and the algorithm name, leaf prefix and node
+//     prefix, each preceded by its length as a varint.
+//
+// The hashes, root and leaf hash must all have the same length, the one of the parameters if there are any.
+func (p *MerkleProof) MarshalBinary() ([]byte, error) {
+	return p.marshalBinary(0)
+}
+
+// marshalBinary encodes the proof with the padding bitmap of a compressed proof, which follows the number of hashes
+// when it isn't zero.
+func (p *MerkleProof) marshalBinary(padding uint64) ([]byte, error) {
+	if len(p.Hashes)+bits.OnesCount64(padding) > maxProofDepth {
+		return nil, ErrProofTooDeep
+	}
+	length, err := p.hashLength()
+	if err != nil {
+		return nil, err
+	}
+	if length > maxProofFieldLength {
+		return nil, errors.New("the proof hash length is out of range")
+	}
+
+	b := make([]byte, 2, 2+4*binary.MaxVarintLen64+(len(p.Hashes)+2)*length)
+	b[0] = proofVersion
+	b = appendUvarint(b, p.Index)
+	b = appendUvarint(b, p.LeafCount)
+	b = appendUvarint(b, uint64(length))
+	b = appendUvarint(b, uint64(len(p.Hashes)))
+	if padding != 0 {
+		b[1] |= proofHasPadding
+		b = appendUvarint(b, padding)
+	}
+	for _, h := range p.Hashes {
+		b = append(b, h...)
+	}
+	if len(p.Root) > 0 {
+		b[1] |= proofHasRoot
+		b = append(b, p.Root...)
+	}
+	if len(p.Leaf) > 0 {
+		b[1] |= proofHasLeaf
+		b = append(b, p.Leaf...)
+	}
+	if p.Params == nil {
+		return b, nil
+	}
+
+	b[1] |= proofHasParams
+	if p.Params.SortedPairs {
+		b[1] |= proofSortedPairs
+	}
+	if p.Params.Shape < ShapeZeroPad || p.Params.Shape > ShapeRFC6962 {
+		return nil, errors.New("unknown tree shape")
+	}
+	b = appendUvarint(b, uint64(p.Params.Shape))
+	for _, field := range [][]byte{[]byte(p.Params.Algorithm), p.Params.Domain.LeafPrefix, p.Params.Domain.NodePrefix} {
+		if len(field) > maxProofFieldLength {
+			return nil, errors.New("the proof parameters are too long")
+		}
+		b = appendUvarint(b, uint64(len(field)))
+		b = append(b, field...)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary decodes a proof encoded by MarshalBinary. The decoding is strict: truncated data, trailing bytes,
+// unknown versions or flags, non-canonical varints and proofs deeper than a tree can be are all errors, so that a
+// proof has a single encoding. A compressed proof is an error; see CompressedProof.
+func (p *MerkleProof) UnmarshalBinary(data []byte) error {
+	proof, padding, err := unmarshalProof(data)
+	if err != nil {
+		return err
+	}
+	if padding != 0 {
+		return errors.New("the proof is compressed")
+	}
+	*p = proof
+	return nil
+}
+
+// unmarshalProof decodes a proof encoded by marshalBinary, returning it with the padding bitmap of a compressed proof.
+func unmarshalProof(data []byte) (MerkleProof, uint64, error) {
+	if len(data) < 2 {
+		return MerkleProof{}, 0, errors.New("the proof is truncated")
+	}
+	if data[0] != proofVersion {
+		return MerkleProof{}, 0, fmt.Errorf("unsupported proof encoding version %d", data[0])
+	}
+	flags := data[1]
+	if flags&^(proofHasParams|proofSortedPairs|proofHasRoot|proofHasLeaf|proofHasPadding) != 0 || flags&(proofHasParams|proofSortedPairs) == proofSortedPairs {
+		return MerkleProof{}, 0, errors.New("the proof has unknown flags")
+	}
+	data = data[2:]
+
+	var header [4]uint64
+	for i := range header {
+		var err error
+		if header[i], data, err = readUvarint(data); err != nil {
+			return MerkleProof{}, 0, err
+		}
+	}
+	index, leafCount, length, count := header[0], header[1], header[2], header[3]
+	var padding uint64
+	if flags&proofHasPadding != 0 {
+		var err error
+		if padding, data, err = readUvarint(data); err != nil {
+			return MerkleProof{}, 0, err
+		}
+		if padding == 0 {
+			return MerkleProof{}, 0, errors.New("the proof has an empty padding")
+		}
+	}
+	if count > maxProofDepth || count+uint64(bits.OnesCount64(padding)) > maxProofDepth {
+		return MerkleProof{}, 0, ErrProofTooDeep
+	}
+	if levels := count + uint64(bits.OnesCount64(padding)); levels < 64 && padding>>levels != 0 {
+		return MerkleProof{}, 0, errPaddingLevels
+	}
+	if length > maxProofFieldLength {
+		return MerkleProof{}, 0, errors.New("the proof hash length is out of range")
+	}
+	// digests is the number of hashes, including the root and the leaf hash.
+	digests := count
+	if flags&proofHasRoot != 0 {
+		digests++
+	}
+	if flags&proofHasLeaf != 0 {
+		digests++
+	}
+	if digests > 0 && length == 0 {
+		return MerkleProof{}, 0, errors.New("the proof hashes are empty")
+	}
+	if digests == 0 && length != 0 && flags&proofHasParams == 0 {
+		// Without parameters, the hash length is the one of the hashes.
+		return MerkleProof{}, 0, errors.New("the proof hash length does not match its hashes")
+	}
+	if digests*length > uint64(len(data)) {
+		return MerkleProof{}, 0, errors.New("the proof is truncated")
+	}
+	// The hashes are copied into one block of memory rather than aliasing data.
+	slab := append([]byte(nil), data[:digests*length]...)
+	data = data[digests*length:]
+	next := func() []byte {
+		h := slab[:length:length]
+		slab = slab[length:]
+		return h
+	}
+	var hashes [][]byte
+	if count > 0 {
+		hashes = make([][]byte, count)
+		for i := range hashes {
+			hashes[i] = next()
+		}
+	}
+	var root, leaf []byte
+	if flags&proofHasRoot != 0 {
+		root = next()
+	}
+	if flags&proofHasLeaf != 0 {
+		leaf = next()
+	}
+
+	var params *TreeParams
+	if flags&proofHasParams != 0 {
+		shape, rest, err := readUvarint(data)
+		if err != nil {
+			return MerkleProof{}, 0, err
+		}
+		if shape > uint64(ShapeRFC6962) {
+			return MerkleProof{}, 0, errors.New("unknown tree shape")
+		}
+		var fields [3][]byte
+		for i := range fields {
+			if fields[i], rest, err = readProofField(rest); err != nil {
+				return MerkleProof{}, 0, err
+			}
+		}
+		data = rest
+		params = &TreeParams{
+			Algorithm:   string(fields[0]),
+			HashLength:  int(length),
+			Shape:       Shape(shape),
+			Domain:      DomainSeparation{LeafPrefix: fields[1], NodePrefix: fields[2]},
+			SortedPairs: flags&proofSortedPairs != 0,
+		}
+	}
+	if len(data) != 0 {
+		return MerkleProof{}, 0, errors.New("the proof has trailing bytes")
+	}
+
+	return MerkleProof{Hashes: hashes, Index: index, LeafCount: leafCount, Params: params, Root: root, Leaf: leaf}, padding, nil
+}
+
+// hashLength returns the length of the hashes of the proof, checking that its hashes, root and leaf hash all have
+// the length of its parameters, or of its first hash without parameters.
+func (p *MerkleProof) hashLength() (int, error) {
+	digests := append([][]byte{p.Root, p.Leaf}, p.Hashes...)
+	length := -1
+	if p.Params != nil {
+		if p.Params.HashLength < 0 {
+			return 0, errors.New("the proof hash length is out of range")
+		}
+		length = p.Params.HashLength
+	}
+	for i, h := range digests {
+		// The root and the leaf hash are optional.
+		if i < 2 && len(h) == 0 {
+			continue
+		}
+		if length < 0 {
+			length = len(h)
+		}
+		if length == 0 {
+			return 0, errors.New("the proof hashes are empty")
+		}
+		if len(h) != length {
+			return 0, ErrHashLength
+		}
+	}
+	if length < 0 {
+		return 0, nil
+	}
+	return length, nil
+}
+
+// appendUvarint appends an unsigned varint to b.
+func appendUvarint(b []byte, n uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], n)]...)
+}
+
+// readUvarint reads an unsigned varint, which must be minimally encoded, and returns it with the rest of the data.
+func readUvarint(data []byte) (uint64, []byte, error) {
+	n, size := binary.Uvarint(data)
+	switch {
+	case size == 0:
+		return 0, nil, errors.New("the proof is truncated")
+	case size < 0:
+		return 0, nil, errors.New("variable length integer overflows 64 bits")
+	case size > 1 && data[size-1] == 0:
+		return 0, nil, errors.New("non-canonical variable length integer")
+	}
+	return n, data[size:], nil
+}
+
+// readProofField reads a byte string preceded by its length, returning nil for an empty string.
+func readProofField(data []byte) ([]byte, []byte, error) {
+	length, data, err := readUvarint(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if length > maxProofFieldLength {
+		return nil, nil, errors.New("the proof parameters are too long")
+	}
+	if length > uint64(len(data)) {
+		return nil, nil, errors.New("the proof is truncated")
+	}
+	if length == 0 {
+		return nil, data, nil
+	}
+	return append([]byte(nil), data[:length]...), data[length:], nil
+}
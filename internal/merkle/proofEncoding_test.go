@@ -0,0 +1,279 @@
+package merkletree_test
+
+import (
+	"bytes"
+	"fmt"
+	merkletree "github.com/reactivejson/merkleTree/internal/merkle"
+	"github.com/reactivejson/merkleTree/internal/merkle/hash"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProofBinary(t *testing.T) {
+	tests := []struct {
+		proof   merkletree.MerkleProof
+		encoded string
+	}{
+		{ // 0
+			proof:   merkletree.MerkleProof{Hashes: [][]byte{{1, 2}, {3, 4}}, Index: 300, LeafCount: 5},
+			encoded: "0100ac0205020201020304",
+		},
+		{ // 1
+			proof: merkletree.MerkleProof{Hashes: [][]byte{{1, 2}, {3, 4}}, Index: 300, LeafCount: 5, Params: &merkletree.TreeParams{
+				Algorithm:   "ab",
+				HashLength:  2,
+				Shape:       merkletree.ShapePromoteOdd,
+				Domain:      merkletree.DomainSeparation{LeafPrefix: []byte{0}, NodePrefix: []byte{1}},
+				SortedPairs: true,
+			}},
+			encoded: "0103ac02050202010203040202616201000101",
+		},
+		{ // 2
+			proof:   merkletree.MerkleProof{Params: &merkletree.TreeParams{Algorithm: "sha256", HashLength: 32}},
+			encoded: "010100002000000673686132353600" + "00",
+		},
+		{ // 3
+			proof:   merkletree.MerkleProof{},
+			encoded: "010000000000",
+		},
+		{ // 4
+			proof:   merkletree.MerkleProof{Hashes: [][]byte{{1, 2}}, Index: 1, LeafCount: 2, Root: []byte{5, 6}, Leaf: []byte{7, 8}},
+			encoded: "010c0102020101020506" + "0708",
+		},
+		{ // 5
+			proof:   merkletree.MerkleProof{Root: []byte{5, 6}},
+			encoded: "0104000002000506",
+		},
+	}
+
+	for i, test := range tests {
+		encoded, err := test.proof.MarshalBinary()
+		assert.NoError(t, err)
+		assert.Equal(t, hexBytes(test.encoded), encoded, fmt.Sprintf("unexpected encoding at test %d", i))
+
+		var decoded merkletree.MerkleProof
+		assert.NoError(t, decoded.UnmarshalBinary(encoded), fmt.Sprintf("failed to decode at test %d", i))
+		assert.Equal(t, test.proof, decoded, fmt.Sprintf("unexpected proof at test %d", i))
+	}
+}
+
+func TestProofBinaryVerify(t *testing.T) {
+	blake3x16, err := hash.NewBlake3WithLength(16)
+	assert.NoError(t, err)
+	tests := []struct {
+		hashType hash.HashType
+		opts     []merkletree.Option
+	}{
+		{hashType: blake3},
+		{hashType: sha256, opts: []merkletree.Option{merkletree.WithShape(merkletree.ShapeRFC6962)}},
+		{hashType: hash.NewKeccak256(), opts: []merkletree.Option{merkletree.WithSortedPairs()}},
+		{hashType: blake3x16, opts: []merkletree.Option{merkletree.WithDomainSeparation([]byte("leaf"), []byte("node"))}},
+	}
+
+	data := leaves(7)
+	for i, test := range tests {
+		tree, err := merkletree.NewTree(data, test.hashType, test.opts...)
+		assert.NoError(t, err)
+		for j, d := range data {
+			proof, err := tree.GenerateMProofAt(uint64(j))
+			assert.NoError(t, err)
+			encoded, err := proof.MarshalBinary()
+			assert.NoError(t, err)
+
+			var decoded merkletree.MerkleProof
+			assert.NoError(t, decoded.UnmarshalBinary(encoded))
+			assert.Equal(t, proof, &decoded, fmt.Sprintf("unexpected proof at test %d input %d", i, j))
+			verified, err := decoded.Verify(d, tree.MerkleRoot())
+			assert.NoError(t, err)
+			assert.True(t, verified, fmt.Sprintf("failed to verify decoded proof at test %d input %d", i, j))
+		}
+	}
+}
+
+func TestProofBinaryErrors(t *testing.T) {
+	marshalTests := []struct {
+		proof merkletree.MerkleProof
+		err   string
+	}{
+		{ // 0
+			proof: merkletree.MerkleProof{Hashes: [][]byte{{1, 2}, {3}}},
+			err:   "the proof hashes do not match the hash length",
+		},
+		{ // 1
+			proof: merkletree.MerkleProof{Hashes: [][]byte{{1, 2}}, Params: &merkletree.TreeParams{HashLength: 32}},
+			err:   "the proof hashes do not match the hash length",
+		},
+		{ // 2
+			proof: merkletree.MerkleProof{Hashes: make([][]byte, 65)},
+			err:   "the proof has too many hashes",
+		},
+		{ // 3
+			proof: merkletree.MerkleProof{Hashes: [][]byte{{}}},
+			err:   "the proof hashes are empty",
+		},
+		{ // 4
+			proof: merkletree.MerkleProof{Params: &merkletree.TreeParams{Shape: merkletree.Shape(7)}},
+			err:   "unknown tree shape",
+		},
+		{ // 5
+			proof: merkletree.MerkleProof{Params: &merkletree.TreeParams{Algorithm: string(make([]byte, 256))}},
+			err:   "the proof parameters are too long",
+		},
+		{ // 6
+			proof: merkletree.MerkleProof{Hashes: [][]byte{{1, 2}}, Root: []byte{1, 2, 3}},
+			err:   "the proof hashes do not match the hash length",
+		},
+		{ // 7
+			proof: merkletree.MerkleProof{Params: &merkletree.TreeParams{HashLength: -1}},
+			err:   "the proof hash length is out of range",
+		},
+	}
+	for i, test := range marshalTests {
+		_, err := test.proof.MarshalBinary()
+		assert.EqualError(t, err, test.err, fmt.Sprintf("unexpected error at test %d", i))
+	}
+
+	unmarshalTests := []struct {
+		encoded string
+		err     string
+	}{
+		{ // 0
+			encoded: "01",
+			err:     "the proof is truncated",
+		},
+		{ // 1
+			encoded: "0200ac0205020201020304",
+			err:     "unsupported proof encoding version 2",
+		},
+		{ // 2
+			encoded: "0120ac0205020201020304",
+			err:     "the proof has unknown flags",
+		},
+		{ // 3
+			encoded: "0102ac0205020201020304",
+			err:     "the proof has unknown flags",
+		},
+		{ // 4
+			encoded: "0100ac02050202010203",
+			err:     "the proof is truncated",
+		},
+		{ // 5
+			encoded: "0100ac020502020102030405",
+			err:     "the proof has trailing bytes",
+		},
+		{ // 6
+			encoded: "01008000050202010203",
+			err:     "non-canonical variable length integer",
+		},
+		{ // 7
+			encoded: "0100ffffffffffffffffff02",
+			err:     "variable length integer overflows 64 bits",
+		},
+		{ // 8
+			encoded: "0100000020" + "41",
+			err:     "the proof has too many hashes",
+		},
+		{ // 9
+			encoded: "010000008002" + "01",
+			err:     "the proof hash length is out of range",
+		},
+		{ // 10
+			encoded: "010000000001",
+			err:     "the proof hashes are empty",
+		},
+		{ // 11
+			encoded: "010000000200",
+			err:     "the proof hash length does not match its hashes",
+		},
+		{ // 12
+			encoded: "01010000020004",
+			err:     "unknown tree shape",
+		},
+		{ // 13
+			encoded: "01010000020000036162",
+			err:     "the proof is truncated",
+		},
+		{ // 14
+			encoded: "010100000200008002",
+			err:     "the proof parameters are too long",
+		},
+		{ // 15
+			encoded: "0100ac02",
+			err:     "the proof is truncated",
+		},
+		{ // 16
+			encoded: "010c010202010102050607",
+			err:     "the proof is truncated",
+		},
+		{ // 17
+			encoded: "010400000000",
+			err:     "the proof hashes are empty",
+		},
+		{ // 18
+			encoded: "01100000000000",
+			err:     "the proof has an empty padding",
+		},
+		{ // 19
+			encoded: "01100000020104" + "0102",
+			err:     "the proof padding is past its levels",
+		},
+	}
+	for i, test := range unmarshalTests {
+		var proof merkletree.MerkleProof
+		err := proof.UnmarshalBinary(hexBytes(test.encoded))
+		assert.EqualError(t, err, test.err, fmt.Sprintf("unexpected error at test %d", i))
+		assert.Equal(t, merkletree.MerkleProof{}, proof, fmt.Sprintf("proof modified at test %d", i))
+	}
+}
+
+func FuzzProofUnmarshalBinary(f *testing.F) {
+	tree, err := merkletree.NewTree(leaves(7), sha256, merkletree.WithShape(merkletree.ShapeRFC6962))
+	if err != nil {
+		f.Fatal(err)
+	}
+	proof, err := tree.GenerateMProofAt(5)
+	if err != nil {
+		f.Fatal(err)
+	}
+	encoded, err := proof.MarshalBinary()
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(encoded)
+	f.Add(hexBytes("0100ac0205020201020304"))
+	f.Add(hexBytes("0103ac02050202010203040202616201000101"))
+	f.Add(hexBytes("010000000000"))
+	f.Add(hexBytes("010c0102020101020506" + "0708"))
+
+	f.Add(hexBytes("011004050201030203"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// A proof has a single encoding, so every decoded proof is encoded back to the same bytes.
+		var compressed merkletree.CompressedProof
+		if err := compressed.UnmarshalBinary(data); err != nil {
+			return
+		}
+		encoded, err := compressed.MarshalBinary()
+		if err != nil {
+			t.Fatalf("failed to encode a decoded compressed proof: %v", err)
+		}
+		if !bytes.Equal(data, encoded) {
+			t.Fatalf("compressed proof %x encoded as %x", data, encoded)
+		}
+
+		var proof merkletree.MerkleProof
+		if err := proof.UnmarshalBinary(data); err != nil {
+			if compressed.Padding == 0 {
+				t.Fatalf("failed to decode a proof without padding: %v", err)
+			}
+			return
+		}
+		if encoded, err = proof.MarshalBinary(); err != nil {
+			t.Fatalf("failed to encode a decoded proof: %v", err)
+		}
+		if !bytes.Equal(data, encoded) {
+			t.Fatalf("proof %x encoded as %x", data, encoded)
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package merkletree
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// proofJSONVersion is the version of the JSON schema of proofs.
+const proofJSONVersion = 1
+
+// proofJSON is the JSON schema of proofs. The tree parameters are only present when the proof carries them, which is
+// told by the shape.
+type proofJSON struct {
+	Version     int        `json:"version"`
+	Algorithm   string     `json:"algorithm,omitempty"`
+	HashLength  int        `json:"hashLength,omitempty"`
+	Shape       string     `json:"shape,omitempty"`
+	LeafPrefix  hexBytes   `json:"leafPrefix,omitempty"`
+	NodePrefix  hexBytes   `json:"nodePrefix,omitempty"`
+	SortedPairs bool       `json:"sortedPairs,omitempty"`
+	Index       uint64     `json:"index"`
+	LeafCount   uint64     `json:"leafCount"`
+	Root        hexBytes   `json:"root,omitempty"`
+	Leaf        hexBytes   `json:"leaf,omitempty"`
+	Hashes      []hexBytes `json:"hashes"`
+}
+
+// hexBytes is a byte array encoded as a 0x-prefixed hex string.
+type hexBytes []byte
+
+// MarshalText encodes the bytes as lowercase hex, prefixed with 0x.
+func (b hexBytes) MarshalText() ([]byte, error) {
+	text := make([]byte, 2+hex.EncodedLen(len(b)))
+	copy(text, "0x")
+	hex.Encode(text[2:], b)
+	return text, nil
+}
+
+// UnmarshalText decodes a 0x-prefixed hex string.
+func (b *hexBytes) UnmarshalText(text []byte) error {
+	if !bytes.HasPrefix(text, []byte("0x")) {
+		return fmt.Errorf("hex string %q has no 0x prefix", text)
+	}
+	decoded, err := hex.DecodeString(string(text[2:]))
+	if err != nil {
+		return fmt.Errorf("invalid hex string %q", text)
+	}
+	*b = decoded
+	return nil
+}
+
+// MarshalJSON encodes the proof as a JSON object with the fields:
+//   - version: 1, the version of the schema;
+//   - algorithm, hashLength, shape, leafPrefix, nodePrefix and sortedPairs: the tree parameters, present when the
+//     proof carries them; the algorithm, the prefixes and sortedPairs are omitted when empty or false;
+//   - index and leafCount: the index of the proven leaf and the number of leaves of the tree;
+//   - root and leaf: the root of the tree and the hash of the proven leaf, when the proof carries them;
+//   - hashes: the hashes of the proof, from the leaf to the root.
+//
+// Every byte array is a lowercase hex string prefixed with 0x.
+func (p *MerkleProof) MarshalJSON() ([]byte, error) {
+	if _, err := p.hashLength(); err != nil {
+		return nil, err
+	}
+	v := proofJSON{
+		Version:   proofJSONVersion,
+		Index:     p.Index,
+		LeafCount: p.LeafCount,
+		Root:      p.Root,
+		Leaf:      p.Leaf,
+		Hashes:    make([]hexBytes, len(p.Hashes)),
+	}
+	for i, h := range p.Hashes {
+		v.Hashes[i] = h
+	}
+	if p.Params != nil {
+		if p.Params.Shape.String() == "unknown" {
+			return nil, errors.New("unknown tree shape")
+		}
+		v.Algorithm = p.Params.Algorithm
+		v.HashLength = p.Params.HashLength
+		v.Shape = p.Params.Shape.String()
+		v.LeafPrefix = p.Params.Domain.LeafPrefix
+		v.NodePrefix = p.Params.Domain.NodePrefix
+		v.SortedPairs = p.Params.SortedPairs
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes a proof encoded by MarshalJSON. Unknown fields, other versions of the schema, hex strings
+// without their 0x prefix and hashes of different lengths are errors.
+func (p *MerkleProof) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var v proofJSON
+	if err := decoder.Decode(&v); err != nil {
+		return err
+	}
+	if v.Version != proofJSONVersion {
+		return fmt.Errorf("unsupported proof schema version %d", v.Version)
+	}
+	if len(v.Hashes) > maxProofDepth {
+		return ErrProofTooDeep
+	}
+
+	proof := MerkleProof{Index: v.Index, LeafCount: v.LeafCount, Root: nilIfEmpty(v.Root), Leaf: nilIfEmpty(v.Leaf)}
+	if len(v.Hashes) > 0 {
+		proof.Hashes = make([][]byte, len(v.Hashes))
+		for i, h := range v.Hashes {
+			proof.Hashes[i] = h
+		}
+	}
+	switch {
+	case v.Shape != "":
+		shape, err := parseShape(v.Shape)
+		if err != nil {
+			return err
+		}
+		proof.Params = &TreeParams{
+			Algorithm:   v.Algorithm,
+			HashLength:  v.HashLength,
+			Shape:       shape,
+			Domain:      DomainSeparation{LeafPrefix: nilIfEmpty(v.LeafPrefix), NodePrefix: nilIfEmpty(v.NodePrefix)},
+			SortedPairs: v.SortedPairs,
+		}
+	case v.Algorithm != "" || v.HashLength != 0 || len(v.LeafPrefix) > 0 || len(v.NodePrefix) > 0 || v.SortedPairs:
+		return errors.New("the proof parameters have no shape")
+	}
+	if _, err := proof.hashLength(); err != nil {
+		return err
+	}
+
+	*p = proof
+	return nil
+}
+
+// parseShape returns the shape of the given name.
+func parseShape(name string) (Shape, error) {
+	for shape := ShapeZeroPad; shape <= ShapeRFC6962; shape++ {
+		if name == shape.String() {
+			return shape, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown tree shape %q", name)
+}
+
+// nilIfEmpty returns nil for an empty byte array, so that absent and empty fields decode alike.
+func nilIfEmpty(b []byte) []byte {
+	if len(b) == 0 {
+		return nil
+	}
+	return b
+}
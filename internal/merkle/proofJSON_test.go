@@ -0,0 +1,115 @@
+package merkletree_test
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	merkletree "github.com/reactivejson/merkleTree/internal/merkle"
+	"github.com/reactivejson/merkleTree/internal/merkle/hash"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update the golden files of testdata")
+
+func TestProofJSON(t *testing.T) {
+	tests := []struct {
+		golden   string
+		hashType hash.HashType
+		opts     []merkletree.Option
+	}{
+		{ // 0
+			golden:   "proof_blake3.json",
+			hashType: blake3,
+		},
+		{ // 1
+			golden:   "proof_sha256_rfc6962.json",
+			hashType: sha256,
+			opts:     []merkletree.Option{merkletree.WithShape(merkletree.ShapeRFC6962)},
+		},
+		{ // 2
+			golden:   "proof_keccak256_sorted.json",
+			hashType: hash.NewKeccak256(),
+			opts:     []merkletree.Option{merkletree.WithSortedPairs(), merkletree.WithShape(merkletree.ShapeDuplicateLast)},
+		},
+	}
+
+	data := leaves(5)
+	for i, test := range tests {
+		tree, err := merkletree.NewTree(data, test.hashType, test.opts...)
+		assert.NoError(t, err)
+		proof, err := tree.GenerateMProofAt(3)
+		assert.NoError(t, err)
+
+		encoded, err := json.MarshalIndent(proof, "", "  ")
+		assert.NoError(t, err)
+		encoded = append(encoded, '\n')
+		golden := filepath.Join("testdata", test.golden)
+		if *update {
+			assert.NoError(t, os.WriteFile(golden, encoded, 0644))
+		}
+		want, err := os.ReadFile(golden)
+		assert.NoError(t, err)
+		assert.Equal(t, string(want), string(encoded), fmt.Sprintf("unexpected encoding at test %d", i))
+
+		var decoded merkletree.MerkleProof
+		assert.NoError(t, json.Unmarshal(want, &decoded), fmt.Sprintf("failed to decode at test %d", i))
+		assert.Equal(t, proof, &decoded, fmt.Sprintf("unexpected proof at test %d", i))
+		verified, err := decoded.Verify(data[3], decoded.Root)
+		assert.NoError(t, err)
+		assert.True(t, verified, fmt.Sprintf("failed to verify decoded proof at test %d", i))
+	}
+}
+
+func TestProofJSONErrors(t *testing.T) {
+	tests := []struct {
+		json string
+		err  string
+	}{
+		{ // 0
+			json: `{"version":2,"index":0,"leafCount":1,"hashes":[]}`,
+			err:  "unsupported proof schema version 2",
+		},
+		{ // 1
+			json: `{"index":0,"leafCount":1,"hashes":[]}`,
+			err:  "unsupported proof schema version 0",
+		},
+		{ // 2
+			json: `{"version":1,"index":0,"leafCount":2,"hashes":["0x0102"],"extra":true}`,
+			err:  `json: unknown field "extra"`,
+		},
+		{ // 3
+			json: `{"version":1,"index":0,"leafCount":2,"hashes":["0102"]}`,
+			err:  `hex string "0102" has no 0x prefix`,
+		},
+		{ // 4
+			json: `{"version":1,"index":0,"leafCount":2,"hashes":["0x012"]}`,
+			err:  `invalid hex string "0x012"`,
+		},
+		{ // 5
+			json: `{"version":1,"index":0,"leafCount":3,"hashes":["0x0102","0x03"]}`,
+			err:  "the proof hashes do not match the hash length",
+		},
+		{ // 6
+			json: `{"version":1,"algorithm":"sha256","hashLength":32,"index":0,"leafCount":2,"hashes":[]}`,
+			err:  "the proof parameters have no shape",
+		},
+		{ // 7
+			json: `{"version":1,"shape":"balanced","index":0,"leafCount":2,"hashes":[]}`,
+			err:  `unknown tree shape "balanced"`,
+		},
+		{ // 8
+			json: `{"version":1,"hashLength":2,"shape":"zero-pad","index":0,"leafCount":2,"root":"0x010203","hashes":["0x0102"]}`,
+			err:  "the proof hashes do not match the hash length",
+		},
+	}
+
+	for i, test := range tests {
+		var proof merkletree.MerkleProof
+		err := json.Unmarshal([]byte(test.json), &proof)
+		assert.EqualError(t, err, test.err, fmt.Sprintf("unexpected error at test %d", i))
+	}
+}
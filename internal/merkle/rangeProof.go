@@ -0,0 +1,130 @@
+package merkletree
+
+import (
+	"bytes"
+	"errors"
+	"github.com/reactivejson/merkleTree/internal/merkle/hash"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// RangeProof is a proof that the leaves [Start, End) are exactly the contents of that span of a Merkle tree.
+// Only the siblings on the left boundary path of the span and on its right boundary path are kept; every node
+// in between is computed from the leaves, so nothing else can sit inside the range.
+type RangeProof struct {
+	Start     uint64   // index of the first leaf in the range
+	End       uint64   // index after the last leaf in the range
+	LeafCount uint64   // the number of leaves in the tree
+	Left      [][]byte // siblings to the left of the range, from the leaves up
+	Right     [][]byte // siblings to the right of the range, from the leaves up
+}
+
+// GenerateRangeProof generates the proof for the contiguous leaves [start, end).
+func (t *MerkleTree) GenerateRangeProof(start, end uint64) (*RangeProof, error) {
+	if start >= end || end > uint64(len(t.data)) {
+		return nil, errors.New("invalid range")
+	}
+
+	proof := &RangeProof{
+		Start:     start,
+		End:       end,
+		LeafCount: uint64(len(t.data)),
+	}
+
+	// lo and hi are the first and last node indexes covered by the range at the current level.
+	width := uint64(len(t.nodes) / 2)
+	for lo, hi := width+start, width+end-1; lo > 1; lo, hi = lo/2, hi/2 {
+		if lo%2 == 1 {
+			proof.Left = append(proof.Left, t.nodes[lo-1])
+		}
+		// A missing sibling is completed by the verifier from the leaf count.
+		if hi%2 == 0 && t.nodes[hi+1] != nil {
+			proof.Right = append(proof.Right, t.nodes[hi+1])
+		}
+	}
+
+	return proof, nil
+}
+
+// VerifyRangeProof verifies a range proof for contiguous pieces of input, given in order.
+// As with VerifyMProof only the root of the tree is needed, and the options must match the ones the tree was built with.
+//
+// This returns true if the proof is verified, otherwise false. An error is returned for a malformed proof.
+func VerifyRangeProof(leaves [][]byte, proof *RangeProof, root []byte, hashType hash.HashType, opts ...Option) (bool, error) {
+	if proof == nil {
+		return false, ErrNilProof
+	}
+	if hashType == nil {
+		return false, ErrNilHashType
+	}
+	cfg := newConfig(opts)
+	if err := cfg.validate(); err != nil {
+		return false, err
+	}
+	if proof.Start >= proof.End || proof.End > proof.LeafCount {
+		return false, errors.New("invalid range")
+	}
+	if uint64(len(leaves)) != proof.End-proof.Start {
+		return false, errors.New("the number of leaves does not match the range")
+	}
+	if err := checkLengths(hashType, root, proof.Left, proof.Right); err != nil {
+		return false, err
+	}
+	width, err := paddedWidth(proof.LeafCount)
+	if err != nil {
+		return false, err
+	}
+	hasher := cfg.hasher(hashType)
+	if err := hasher.validate(leaves...); err != nil {
+		return false, err
+	}
+
+	level := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		level[i] = hasher.leaf(leaf)
+	}
+
+	// start is the index of the first node of the current level, and count the number of nodes in that level.
+	start, count := width, width
+	if cfg.shape != ShapeZeroPad {
+		count = proof.LeafCount
+	}
+
+	left, right := proof.Left, proof.Right
+	for lo, hi := width+proof.Start, width+proof.End-1; lo > 1; lo, hi, start, count = lo/2, hi/2, start/2, (count+1)/2 {
+		// Complete the level with the boundary siblings, so that it starts with a left child and ends with a right child.
+		if lo%2 == 1 {
+			if len(left) == 0 {
+				return false, errors.New("not enough left hashes in the proof")
+			}
+			level = append([][]byte{left[0]}, level...)
+			left = left[1:]
+		}
+		if hi%2 == 0 {
+			switch {
+			case hi+1-start >= count:
+				// The last node of an odd level has no sibling.
+				level = append(level, nil)
+			case len(right) == 0:
+				return false, errors.New("not enough right hashes in the proof")
+			default:
+				level = append(level, right[0])
+				right = right[1:]
+			}
+		}
+
+		parents := make([][]byte, len(level)/2)
+		for i := range parents {
+			parents[i] = hasher.branch(level[2*i], level[2*i+1])
+		}
+		level = parents
+	}
+	if len(left) != 0 || len(right) != 0 {
+		return false, errors.New("too many hashes in the proof")
+	}
+
+	return bytes.Equal(root, level[0]), nil
+}
@@ -0,0 +1,89 @@
+package merkletree_test
+
+import (
+	"fmt"
+	merkletree "github.com/reactivejson/merkleTree/internal/merkle"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+func TestRangeProof(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		data := leaves(n)
+		tree, err := merkletree.NewTree(data, blake3, merkletree.WithRFC6962Prefixes())
+		assert.NoError(t, err)
+
+		for start := 0; start < n; start++ {
+			for end := start + 1; end <= n; end++ {
+				proof, err := tree.GenerateRangeProof(uint64(start), uint64(end))
+				assert.NoError(t, err)
+
+				ok, err := merkletree.VerifyRangeProof(data[start:end], proof, tree.MerkleRoot(), blake3, merkletree.WithRFC6962Prefixes())
+				assert.NoError(t, err)
+				assert.True(t, ok, fmt.Sprintf("failed to verify range [%d, %d) of %d", start, end, n))
+
+				// Swapping two leaves of the range is detected.
+				if end-start > 1 {
+					swapped := append([][]byte(nil), data[start:end]...)
+					swapped[0], swapped[1] = swapped[1], swapped[0]
+					ok, err = merkletree.VerifyRangeProof(swapped, proof, tree.MerkleRoot(), blake3, merkletree.WithRFC6962Prefixes())
+					assert.NoError(t, err)
+					assert.False(t, ok, fmt.Sprintf("verified swapped range [%d, %d) of %d", start, end, n))
+				}
+			}
+		}
+	}
+}
+
+func TestRangeProofSize(t *testing.T) {
+	tree, err := merkletree.NewTree(leaves(1024), blake3)
+	assert.NoError(t, err)
+
+	// A page of 100 entries only needs the hashes around its two boundaries.
+	proof, err := tree.GenerateRangeProof(300, 400)
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, len(proof.Left)+len(proof.Right), 2*10)
+}
+
+func TestRangeProofErrors(t *testing.T) {
+	data := leaves(5)
+	tree, err := merkletree.NewTree(data, blake3)
+	assert.NoError(t, err)
+
+	_, err = tree.GenerateRangeProof(2, 2)
+	assert.EqualError(t, err, "invalid range")
+	_, err = tree.GenerateRangeProof(2, 6)
+	assert.EqualError(t, err, "invalid range")
+
+	proof, err := tree.GenerateRangeProof(1, 3)
+	assert.NoError(t, err)
+
+	// Leaving a leaf out, or adding one, does not match the proven range.
+	_, err = merkletree.VerifyRangeProof(data[1:2], proof, tree.MerkleRoot(), blake3)
+	assert.EqualError(t, err, "the number of leaves does not match the range")
+	_, err = merkletree.VerifyRangeProof(data[1:4], proof, tree.MerkleRoot(), blake3)
+	assert.EqualError(t, err, "the number of leaves does not match the range")
+
+	short := *proof
+	short.Left = nil
+	_, err = merkletree.VerifyRangeProof(data[1:3], &short, tree.MerkleRoot(), blake3)
+	assert.EqualError(t, err, "not enough left hashes in the proof")
+
+	long := *proof
+	long.Right = append(append([][]byte(nil), proof.Right...), proof.Right[0])
+	_, err = merkletree.VerifyRangeProof(data[1:3], &long, tree.MerkleRoot(), blake3)
+	assert.EqualError(t, err, "too many hashes in the proof")
+
+	// A leaf count past 1<<63 has no padded width, and must not make the verifier loop forever.
+	huge := *proof
+	huge.LeafCount = 1<<63 + 1
+	_, err = merkletree.VerifyRangeProof(data[1:3], &huge, tree.MerkleRoot(), blake3)
+	assert.EqualError(t, err, "the proof leaf count is too large")
+	assert.ErrorIs(t, err, merkletree.ErrMalformedProof)
+}
@@ -0,0 +1,184 @@
+package merkletree_test
+
+import (
+	"fmt"
+	merkletree "github.com/reactivejson/merkleTree/internal/merkle"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+var shapes = []merkletree.Shape{
+	merkletree.ShapeZeroPad,
+	merkletree.ShapeDuplicateLast,
+	merkletree.ShapePromoteOdd,
+	merkletree.ShapeRFC6962,
+}
+
+func TestShapeRoots(t *testing.T) {
+	data := leaves(3)
+	a, b, c := blake3.Hash(data[0]), blake3.Hash(data[1]), blake3.Hash(data[2])
+
+	tests := []struct {
+		shape merkletree.Shape
+		root  []byte
+	}{
+		{
+			shape: merkletree.ShapeZeroPad,
+			root:  blake3.Hash(blake3.Hash(a, b), blake3.Hash(c, make([]byte, 32))),
+		},
+		{
+			shape: merkletree.ShapeDuplicateLast,
+			root:  blake3.Hash(blake3.Hash(a, b), blake3.Hash(c, c)),
+		},
+		{
+			shape: merkletree.ShapePromoteOdd,
+			root:  blake3.Hash(blake3.Hash(a, b), c),
+		},
+	}
+
+	for _, test := range tests {
+		tree, err := merkletree.NewTree(data, blake3, merkletree.WithShape(test.shape))
+		assert.NoError(t, err)
+		assert.Equal(t, test.shape, tree.Shape())
+		assert.Equal(t, test.root, tree.MerkleRoot(), fmt.Sprintf("unexpected root for shape %s", test.shape))
+	}
+}
+
+func TestShapeRFC6962(t *testing.T) {
+	for size := 1; size <= len(ctLeaves); size++ {
+		tree, err := merkletree.NewTree(ctLeaves[:size], sha256, merkletree.WithShape(merkletree.ShapeRFC6962))
+		assert.NoError(t, err)
+		assert.Equal(t, ctRoots[size-1], tree.MerkleRoot(), fmt.Sprintf("unexpected root for size %d", size))
+
+		root, err := tree.RootAt(uint64(size))
+		assert.NoError(t, err)
+		assert.Equal(t, tree.MerkleRoot(), root)
+	}
+
+	tree, err := merkletree.NewTree(ctLeaves, sha256, merkletree.WithShape(merkletree.ShapeRFC6962))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x00}, tree.DomainSeparation().LeafPrefix)
+	assert.Equal(t, []byte{0x01}, tree.DomainSeparation().NodePrefix)
+}
+
+func TestShapeProofs(t *testing.T) {
+	for _, shape := range shapes {
+		for n := 1; n <= 9; n++ {
+			data := leaves(n)
+			tree, err := merkletree.NewTree(data, blake3, merkletree.WithShape(shape))
+			assert.NoError(t, err)
+
+			for i, d := range data {
+				proof, err := tree.GenerateMProof(d)
+				assert.NoError(t, err)
+				ok, err := merkletree.VerifyMProof(d, proof, tree.MerkleRoot(), blake3, merkletree.WithShape(shape))
+				assert.NoError(t, err)
+				assert.True(t, ok, fmt.Sprintf("failed to verify proof for input %d of %d with shape %s", i, n, shape))
+			}
+
+			indices := []uint64{0, uint64(n - 1)}
+			if n == 1 {
+				indices = indices[:1]
+			}
+			multi, err := tree.GenerateMultiProof(indices)
+			assert.NoError(t, err)
+			proven := make([][]byte, len(multi.Indices))
+			for j, index := range multi.Indices {
+				proven[j] = data[index]
+			}
+			ok, err := merkletree.VerifyMultiProof(proven, multi, tree.MerkleRoot(), blake3, merkletree.WithShape(shape))
+			assert.NoError(t, err)
+			assert.True(t, ok, fmt.Sprintf("failed to verify multiproof of %d with shape %s", n, shape))
+
+			for start := 0; start < n; start++ {
+				rangeProof, err := tree.GenerateRangeProof(uint64(start), uint64(n))
+				assert.NoError(t, err)
+				ok, err = merkletree.VerifyRangeProof(data[start:], rangeProof, tree.MerkleRoot(), blake3, merkletree.WithShape(shape))
+				assert.NoError(t, err)
+				assert.True(t, ok, fmt.Sprintf("failed to verify range [%d, %d) with shape %s", start, n, shape))
+			}
+		}
+	}
+}
+
+func TestShapeMutations(t *testing.T) {
+	for _, shape := range shapes {
+		tree, err := merkletree.NewTree(leaves(3), blake3, merkletree.WithShape(shape))
+		assert.NoError(t, err)
+
+		tree.Append(leaves(7)[3:]...)
+		_, _, err = tree.InsertLeaf(2, []byte("inserted"))
+		assert.NoError(t, err)
+		_, _, err = tree.RemoveLeaf(7)
+		assert.NoError(t, err)
+		_, _, err = tree.RemoveLeaf(0)
+		assert.NoError(t, err)
+		assert.NoError(t, tree.UpdateLeaf(1, []byte("updated")))
+		assert.NoError(t, tree.UpdateLeaves(map[uint64][]byte{3: []byte("batched")}))
+
+		expectedData := [][]byte{
+			[]byte("leaf-1"),
+			[]byte("updated"),
+			[]byte("leaf-2"),
+			[]byte("batched"),
+			[]byte("leaf-4"),
+			[]byte("leaf-5"),
+		}
+		expected, err := merkletree.NewTree(expectedData, blake3, merkletree.WithShape(shape))
+		assert.NoError(t, err)
+		assert.Equal(t, expected.MerkleRoot(), tree.MerkleRoot(), fmt.Sprintf("unexpected root with shape %s", shape))
+
+		proof, err := tree.GenerateMProof([]byte("leaf-4"))
+		assert.NoError(t, err)
+		ok, err := merkletree.VerifyMProof([]byte("leaf-4"), proof, tree.MerkleRoot(), blake3, merkletree.WithShape(shape))
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	}
+}
+
+func TestShapeProofErrors(t *testing.T) {
+	data := leaves(5)
+	tree, err := merkletree.NewTree(data, blake3, merkletree.WithShape(merkletree.ShapeDuplicateLast))
+	assert.NoError(t, err)
+
+	// The last leaf is paired with itself, which must not make a proof for a leaf past the end.
+	proof, err := tree.GenerateMProof(data[4])
+	assert.NoError(t, err)
+	forged := *proof
+	forged.Index = 5
+	_, err = merkletree.VerifyMProof(data[4], &forged, tree.MerkleRoot(), blake3, merkletree.WithShape(merkletree.ShapeDuplicateLast))
+	assert.EqualError(t, err, "index out of bounds")
+
+	forged = *proof
+	forged.LeafCount = 0
+	_, err = merkletree.VerifyMProof(data[4], &forged, tree.MerkleRoot(), blake3, merkletree.WithShape(merkletree.ShapeDuplicateLast))
+	assert.EqualError(t, err, "the proof has no leaf count")
+
+	forged = *proof
+	forged.Hashes = append(append([][]byte(nil), proof.Hashes...), proof.Hashes[0])
+	_, err = merkletree.VerifyMProof(data[4], &forged, tree.MerkleRoot(), blake3, merkletree.WithShape(merkletree.ShapeDuplicateLast))
+	assert.EqualError(t, err, "too many hashes in the proof")
+
+	_, err = merkletree.NewTree(data, blake3, merkletree.WithShape(merkletree.Shape(42)))
+	assert.EqualError(t, err, "unknown tree shape")
+}
+
+func TestShapeVisual(t *testing.T) {
+	tree, err := merkletree.NewTree(leaves(3), blake3, merkletree.WithShape(merkletree.ShapePromoteOdd))
+	assert.NoError(t, err)
+	proof, err := tree.GenerateMProofAt(2)
+	assert.NoError(t, err)
+
+	graph := tree.VisualProof(proof, new(merkletree.StringFormatter), nil)
+	assert.True(t, strings.HasPrefix(graph, "digraph MerkleTree {"))
+	// Leaf 3 of the padded layout does not exist, so it is not drawn.
+	assert.NotContains(t, graph, "7 [label=")
+	assert.Contains(t, graph, "6 [label=")
+}
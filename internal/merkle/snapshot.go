@@ -0,0 +1,384 @@
+package merkletree
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	hash2 "github.com/reactivejson/merkleTree/internal/merkle/hash"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// snapshotMagic starts every snapshot of a tree, followed by the version of its format.
+const (
+	snapshotMagic   = "MRKL"
+	snapshotVersion = 1
+)
+
+// The flags of a snapshot.
+const (
+	snapshotSortedPairs = 1 << 0
+	snapshotNodes       = 1 << 1
+)
+
+// snapshotChecksum is the table of the CRC-32C checksum ending every snapshot.
+var snapshotChecksum = crc32.MakeTable(crc32.Castagnoli)
+
+// SnapshotOption changes how a tree is written by WriteSnapshot.
+type SnapshotOption func(*snapshotConfig)
+
+type snapshotConfig struct {
+	skipNodes bool
+}
+
+// WithoutNodes leaves the nodes out of the snapshot, which then holds the data of the leaves only: the snapshot is
+// smaller, but ReadTree hashes the whole tree again.
+func WithoutNodes() SnapshotOption {
+	return func(c *snapshotConfig) {
+		c.skipNodes = true
+	}
+}
+
+// WriteTo writes a snapshot of the tree, with its nodes, to w; see WriteSnapshot.
+func (t *MerkleTree) WriteTo(w io.Writer) (int64, error) {
+	return t.WriteSnapshot(w)
+}
+
+// WriteSnapshot writes a snapshot of the tree to w, which ReadTree restores. The snapshot is made of:
+//   - the magic "MRKL", the version byte, 1, and a byte of flags: whether the tree sorts its pairs, and whether the
+//     nodes are stored;
+//   - the shape as an unsigned varint, the name of the hash type in the registry, its digest length as an unsigned
+//     varint, and the leaf and node prefixes;
+//   - the number of leaves as an unsigned varint, followed by their data;
+//   - unless WithoutNodes is given, every node from the root to the last leaf, level by level, as a byte telling
+//     whether the node exists followed by its hash;
+//   - the CRC-32C checksum of all of the above, big-endian.
+//
+// The byte strings are preceded by their length as an unsigned varint. It returns the number of bytes written.
+func (t *MerkleTree) WriteSnapshot(w io.Writer, opts ...SnapshotOption) (int64, error) {
+	var cfg snapshotConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	sw := &snapshotWriter{w: bufio.NewWriter(w), crc: crc32.New(snapshotChecksum)}
+	var flags byte
+	if t.sortedPairs {
+		flags |= snapshotSortedPairs
+	}
+	if !cfg.skipNodes {
+		flags |= snapshotNodes
+	}
+	sw.write(append([]byte(snapshotMagic), snapshotVersion, flags))
+	sw.writeUvarint(uint64(t.shape))
+	sw.writeField([]byte(hash2.NameOf(t.hash)))
+	sw.writeUvarint(uint64(t.hash.HashLength()))
+	sw.writeField(t.domain.LeafPrefix)
+	sw.writeField(t.domain.NodePrefix)
+
+	sw.writeUvarint(uint64(len(t.data)))
+	for _, d := range t.data {
+		sw.writeField(d)
+	}
+	if !cfg.skipNodes {
+		for _, node := range t.nodes[1 : len(t.nodes)/2+len(t.data)] {
+			if node == nil {
+				sw.write([]byte{0})
+				continue
+			}
+			sw.write([]byte{1})
+			sw.write(node)
+		}
+	}
+
+	var checksum [4]byte
+	binary.BigEndian.PutUint32(checksum[:], sw.crc.Sum32())
+	sw.write(checksum[:])
+	if sw.err == nil {
+		sw.err = sw.w.Flush()
+	}
+	return sw.n, sw.err
+}
+
+// ReadTree restores a tree from a snapshot written by WriteTo or WriteSnapshot, with the hash type it was built with.
+// The shape, domain separation and pair ordering are read from the snapshot; opts may set the number of workers,
+// but options contradicting the snapshot are an error. A snapshot written by another hash type, or whose checksum
+// doesn't match, is an error. Without its nodes, the tree is hashed again.
+//
+// The checksum only catches accidental corruption. The leaves are hashed again and checked against the stored
+// leaf nodes, so the data always matches the leaves, but the interior nodes are trusted: a snapshot edited on disk
+// can restore a tree whose root doesn't cover its leaves. Snapshots from untrusted sources should be written
+// WithoutNodes, which are then hashed again.
+func ReadTree(r io.Reader, hashType hash2.HashType, opts ...Option) (*MerkleTree, error) {
+	if hashType == nil {
+		return nil, errors.New("please specify hash algo")
+	}
+	sr := &snapshotReader{r: bufio.NewReader(r), crc: crc32.New(snapshotChecksum)}
+
+	header := sr.read(len(snapshotMagic) + 2)
+	if sr.err != nil {
+		return nil, sr.err
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, errors.New("not a merkle tree snapshot")
+	}
+	if version := header[len(snapshotMagic)]; version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", version)
+	}
+	flags := header[len(snapshotMagic)+1]
+	if flags&^(snapshotSortedPairs|snapshotNodes) != 0 {
+		return nil, errors.New("the snapshot has unknown flags")
+	}
+
+	params := TreeParams{
+		Shape:       Shape(sr.readUvarint()),
+		Algorithm:   string(sr.readField()),
+		HashLength:  int(sr.readUvarint()),
+		SortedPairs: flags&snapshotSortedPairs != 0,
+	}
+	params.Domain = DomainSeparation{LeafPrefix: nilIfEmpty(sr.readField()), NodePrefix: nilIfEmpty(sr.readField())}
+	if sr.err != nil {
+		return nil, sr.err
+	}
+	if params.Algorithm != hash2.NameOf(hashType) {
+		return nil, fmt.Errorf("the snapshot was written with hash algorithm %q", params.Algorithm)
+	}
+	if params.HashLength != hashType.HashLength() {
+		return nil, fmt.Errorf("the snapshot was written with a hash length of %d bytes", params.HashLength)
+	}
+	cfg := newConfig(append(params.options(), opts...))
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.shape != params.Shape || cfg.sortedPairs != params.SortedPairs ||
+		!bytes.Equal(cfg.domain.LeafPrefix, params.Domain.LeafPrefix) || !bytes.Equal(cfg.domain.NodePrefix, params.Domain.NodePrefix) {
+		return nil, errors.New("the options contradict the snapshot")
+	}
+
+	n := sr.readUvarint()
+	if sr.err == nil && n == 0 {
+		return nil, errors.New("the merkle tree should contains at least 1 piece of input")
+	}
+	// The data is read before anything is allocated for it, so that a corrupted count fails on the missing data.
+	var data [][]byte
+	for i := uint64(0); i < n && sr.err == nil; i++ {
+		data = append(data, sr.readField())
+	}
+
+	width := 1
+	for sr.err == nil && width < len(data) {
+		width *= 2
+	}
+	var nodes [][]byte
+	if flags&snapshotNodes != 0 && sr.err == nil {
+		nodes = sr.readNodes(width, len(data), params.HashLength)
+	}
+
+	var checksum [4]byte
+	binary.BigEndian.PutUint32(checksum[:], sr.crc.Sum32())
+	stored := sr.read(len(checksum))
+	if sr.err != nil {
+		return nil, sr.err
+	}
+	if !bytes.Equal(stored, checksum[:]) {
+		return nil, errors.New("the snapshot checksum does not match")
+	}
+
+	if nodes == nil {
+		return NewTree(data, hashType, cfg.options()...)
+	}
+	return restoreTree(data, nodes, hashType, cfg)
+}
+
+// restoreTree returns the tree of the data with the nodes of a snapshot, checking that they have the layout of the
+// shape and that every leaf node is the hash of its data, which also catches a keyed hash type with another key.
+// The interior nodes are not hashed again.
+func restoreTree(data, nodes [][]byte, hashType hash2.HashType, cfg config) (*MerkleTree, error) {
+	width := len(nodes) / 2
+	if cfg.shape == ShapeZeroPad {
+		pad := make([]byte, hashType.HashLength())
+		for i := width + len(data); i < len(nodes); i++ {
+			nodes[i] = pad
+		}
+	}
+	for i := 1; i < width+len(data); i++ {
+		// A node exists if its left child does, and every leaf exists.
+		exists := i >= width || nodes[2*i] != nil
+		if (nodes[i] != nil) != exists {
+			return nil, errors.New("the snapshot nodes do not match the shape of the tree")
+		}
+	}
+
+	hasher := cfg.hasher(hashType)
+	if err := hasher.validate(data...); err != nil {
+		return nil, err
+	}
+	leaves := make([][]byte, len(data))
+	createLeaves(data, leaves, hasher, cfg.workers)
+	for i, leaf := range leaves {
+		if !bytes.Equal(leaf, nodes[width+i]) {
+			return nil, errors.New("the snapshot leaves do not match their data and the hash type")
+		}
+	}
+	tree := &MerkleTree{
+		hash:        hashType,
+		domain:      cfg.domain,
+		shape:       cfg.shape,
+		sortedPairs: cfg.sortedPairs,
+		nodes:       nodes,
+		data:        data,
+		workers:     cfg.workers,
+		pool:        hasher.pool,
+	}
+	tree.indexLeaves()
+	return tree, nil
+}
+
+// options returns the options to build a tree with this configuration.
+func (c config) options() []Option {
+	opts := []Option{WithShape(c.shape)}
+	if c.workers > 0 {
+		opts = append(opts, WithWorkers(c.workers))
+	}
+	if c.domain.Enabled() {
+		opts = append(opts, WithDomainSeparation(c.domain.LeafPrefix, c.domain.NodePrefix))
+	}
+	if c.sortedPairs {
+		opts = append(opts, WithSortedPairs())
+	}
+	return opts
+}
+
+// snapshotWriter writes a snapshot, keeping its checksum, the number of bytes written and the first error.
+type snapshotWriter struct {
+	w   *bufio.Writer
+	crc hash.Hash32
+	n   int64
+	err error
+}
+
+func (w *snapshotWriter) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	w.crc.Write(b)
+	n, err := w.w.Write(b)
+	w.n += int64(n)
+	w.err = err
+}
+
+func (w *snapshotWriter) writeUvarint(n uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	w.write(buf[:binary.PutUvarint(buf[:], n)])
+}
+
+func (w *snapshotWriter) writeField(b []byte) {
+	w.writeUvarint(uint64(len(b)))
+	w.write(b)
+}
+
+// snapshotReader reads a snapshot, keeping its checksum and the first error.
+type snapshotReader struct {
+	r   *bufio.Reader
+	crc hash.Hash32
+	err error
+}
+
+// ReadByte lets binary.ReadUvarint read from the snapshot.
+func (r *snapshotReader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	r.crc.Write([]byte{b})
+	return b, nil
+}
+
+func (r *snapshotReader) fail(err error) {
+	if r.err != nil {
+		return
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = errors.New("the snapshot is truncated")
+	}
+	r.err = err
+}
+
+func (r *snapshotReader) read(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		r.fail(err)
+		return nil
+	}
+	r.crc.Write(b)
+	return b
+}
+
+func (r *snapshotReader) readUvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		r.fail(err)
+	}
+	return n
+}
+
+// readField reads a byte string preceded by its length. Large strings are read as they come rather than allocated
+// upfront, so that a corrupted length fails on the missing bytes.
+func (r *snapshotReader) readField() []byte {
+	n := r.readUvarint()
+	if r.err != nil {
+		return nil
+	}
+	if n <= 1<<16 {
+		return r.read(int(n))
+	}
+	var buf bytes.Buffer
+	if n > 1<<62 {
+		r.fail(io.ErrUnexpectedEOF)
+		return nil
+	}
+	if _, err := io.CopyN(io.MultiWriter(&buf, r.crc), r.r, int64(n)); err != nil {
+		r.fail(err)
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// readNodes reads the nodes from the root to the last leaf of a tree of the given padded width, into a single block
+// of memory.
+func (r *snapshotReader) readNodes(width, leaves, hashLength int) [][]byte {
+	nodes := make([][]byte, 2*width)
+	slab := make([]byte, (width+leaves-1)*hashLength)
+	for i := 1; i < width+leaves && r.err == nil; i++ {
+		exists := r.read(1)
+		switch {
+		case r.err != nil:
+		case exists[0] == 0:
+		case exists[0] == 1:
+			node := slab[(i-1)*hashLength : i*hashLength : i*hashLength]
+			if _, err := io.ReadFull(r.r, node); err != nil {
+				r.fail(err)
+				break
+			}
+			r.crc.Write(node)
+			nodes[i] = node
+		default:
+			r.fail(errors.New("the snapshot nodes are invalid"))
+		}
+	}
+	return nodes
+}
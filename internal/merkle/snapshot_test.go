@@ -0,0 +1,248 @@
+package merkletree_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	merkletree "github.com/reactivejson/merkleTree/internal/merkle"
+	"github.com/reactivejson/merkleTree/internal/merkle/hash"
+	"hash/crc32"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	options := [][]merkletree.Option{
+		nil,
+		{merkletree.WithRFC6962Prefixes()},
+		{merkletree.WithSortedPairs()},
+	}
+
+	for i, shape := range shapes {
+		for j, opts := range options {
+			opts = append([]merkletree.Option{merkletree.WithShape(shape)}, opts...)
+			for n := 1; n <= 17; n++ {
+				data := leaves(n)
+				tree, err := merkletree.NewTree(data, blake3, opts...)
+				assert.NoError(t, err)
+				var full bytes.Buffer
+				_, err = tree.WriteTo(&full)
+				assert.NoError(t, err)
+
+				for _, snapshotOpts := range [][]merkletree.SnapshotOption{nil, {merkletree.WithoutNodes()}} {
+					var buf bytes.Buffer
+					_, err := tree.WriteSnapshot(&buf, snapshotOpts...)
+					assert.NoError(t, err)
+					restored, err := merkletree.ReadTree(&buf, blake3)
+					assert.NoError(t, err, fmt.Sprintf("failed to read snapshot at test %d-%d with %d leaves", i, j, n))
+					assert.Equal(t, tree.MerkleRoot(), restored.MerkleRoot(), fmt.Sprintf("unexpected root at test %d-%d with %d leaves", i, j, n))
+					assert.Equal(t, tree.Params(), restored.Params(), fmt.Sprintf("unexpected params at test %d-%d with %d leaves", i, j, n))
+
+					// Written again with its nodes, the restored tree gives the snapshot of the original one.
+					var again bytes.Buffer
+					_, err = restored.WriteTo(&again)
+					assert.NoError(t, err)
+					assert.Equal(t, full.Bytes(), again.Bytes(), fmt.Sprintf("unexpected snapshot at test %d-%d with %d leaves", i, j, n))
+
+					proof, err := restored.GenerateMProof(data[n-1])
+					assert.NoError(t, err)
+					want, err := tree.GenerateMProofAt(uint64(n - 1))
+					assert.NoError(t, err)
+					assert.Equal(t, want, proof, fmt.Sprintf("unexpected proof at test %d-%d with %d leaves", i, j, n))
+				}
+			}
+		}
+	}
+}
+
+func TestSnapshotAfterMutations(t *testing.T) {
+	tree, err := merkletree.NewTree(leaves(5), blake3)
+	assert.NoError(t, err)
+	tree.Append([]byte("leaf-5"), []byte("leaf-6"), []byte("leaf-7"), []byte("leaf-8"))
+	_, _, err = tree.RemoveLeaf(2)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	n, err := tree.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+	restored, err := merkletree.ReadTree(&buf, blake3)
+	assert.NoError(t, err)
+	assert.Equal(t, tree.MerkleRoot(), restored.MerkleRoot())
+
+	// The restored tree can be changed further, as the original one.
+	tree.Append([]byte("leaf-9"))
+	restored.Append([]byte("leaf-9"))
+	assert.Equal(t, tree.MerkleRoot(), restored.MerkleRoot())
+	_, err = restored.GenerateMProof([]byte("leaf-2"))
+	assert.EqualError(t, err, "data not found")
+}
+
+func TestSnapshotWithoutNodesSize(t *testing.T) {
+	tree, err := merkletree.NewTree(leaves(5), blake3)
+	assert.NoError(t, err)
+	var full, small bytes.Buffer
+	_, err = tree.WriteTo(&full)
+	assert.NoError(t, err)
+	_, err = tree.WriteSnapshot(&small, merkletree.WithoutNodes())
+	assert.NoError(t, err)
+	// Every node from the root to the last leaf is a presence byte and a hash.
+	assert.Equal(t, full.Len()-12*33, small.Len())
+}
+
+// resum replaces the checksum ending a snapshot by the one of its new content.
+func resum(snapshot []byte) []byte {
+	end := len(snapshot) - 4
+	binary.BigEndian.PutUint32(snapshot[end:], crc32.Checksum(snapshot[:end], crc32.MakeTable(crc32.Castagnoli)))
+	return snapshot
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	tree, err := merkletree.NewTree(leaves(5), blake3)
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	_, err = tree.WriteTo(&buf)
+	assert.NoError(t, err)
+	snapshot := buf.Bytes()
+	// The nodes from the root to the last leaf come before the checksum.
+	nodes := len(snapshot) - 4 - 12*33
+
+	var key, otherKey [32]byte
+	copy(key[:], "tenant key of thirty-two bytes!!")
+	copy(otherKey[:], "another key of thirty-two bytes!")
+	keyed, err := merkletree.NewTree(leaves(5), hash.NewBlake3Keyed(key))
+	assert.NoError(t, err)
+	var keyedSnapshot bytes.Buffer
+	_, err = keyed.WriteTo(&keyedSnapshot)
+	assert.NoError(t, err)
+	shortBlake3, err := hash.NewBlake3WithLength(16)
+	assert.NoError(t, err)
+
+	change := func(at int, b byte) []byte {
+		changed := append([]byte(nil), snapshot...)
+		changed[at] = b
+		return changed
+	}
+
+	tests := []struct {
+		snapshot []byte
+		hashType hash.HashType
+		opts     []merkletree.Option
+		err      string
+	}{
+		{ // 0
+			snapshot: nil,
+			err:      "the snapshot is truncated",
+		},
+		{ // 1
+			snapshot: change(0, 'X'),
+			err:      "not a merkle tree snapshot",
+		},
+		{ // 2
+			snapshot: change(4, 2),
+			err:      "unsupported snapshot version 2",
+		},
+		{ // 3
+			snapshot: change(5, 0x82),
+			err:      "the snapshot has unknown flags",
+		},
+		{ // 4
+			snapshot: snapshot[:len(snapshot)-1],
+			err:      "the snapshot is truncated",
+		},
+		{ // 5
+			snapshot: snapshot[:nodes+40],
+			err:      "the snapshot is truncated",
+		},
+		{ // 6
+			snapshot: change(nodes+1, snapshot[nodes+1]^1),
+			err:      "the snapshot checksum does not match",
+		},
+		{ // 7
+			snapshot: resum(change(nodes, 2)),
+			err:      "the snapshot nodes are invalid",
+		},
+		{ // 8
+			// The root is marked missing and its hash left out.
+			snapshot: resum(append(append(append([]byte(nil), snapshot[:nodes]...), 0), snapshot[nodes+33:]...)),
+			err:      "the snapshot nodes do not match the shape of the tree",
+		},
+		{ // 9
+			snapshot: snapshot,
+			hashType: sha256,
+			err:      `the snapshot was written with hash algorithm "blake3"`,
+		},
+		{ // 10
+			snapshot: snapshot,
+			hashType: shortBlake3,
+			err:      "the snapshot was written with a hash length of 32 bytes",
+		},
+		{ // 11
+			snapshot: keyedSnapshot.Bytes(),
+			hashType: hash.NewBlake3Keyed(otherKey),
+			err:      "the snapshot leaves do not match their data and the hash type",
+		},
+		{ // 12
+			// The data of the last leaf, "leaf-4", is edited and the checksum made to match.
+			snapshot: resum(change(nodes-1, '5')),
+			err:      "the snapshot leaves do not match their data and the hash type",
+		},
+		{ // 13
+			snapshot: snapshot,
+			opts:     []merkletree.Option{merkletree.WithSortedPairs()},
+			err:      "the options contradict the snapshot",
+		},
+		{ // 14
+			snapshot: snapshot,
+			opts:     []merkletree.Option{merkletree.WithShape(merkletree.ShapeRFC6962)},
+			err:      "the options contradict the snapshot",
+		},
+	}
+
+	for i, test := range tests {
+		hashType := test.hashType
+		if hashType == nil {
+			hashType = blake3
+		}
+		restored, err := merkletree.ReadTree(bytes.NewReader(test.snapshot), hashType, test.opts...)
+		assert.EqualError(t, err, test.err, fmt.Sprintf("unexpected error at test %d", i))
+		assert.Nil(t, restored, fmt.Sprintf("unexpected tree at test %d", i))
+	}
+
+	// The number of workers doesn't change the tree, and may be given.
+	restored, err := merkletree.ReadTree(bytes.NewReader(snapshot), blake3, merkletree.WithWorkers(4))
+	assert.NoError(t, err)
+	assert.Equal(t, tree.MerkleRoot(), restored.MerkleRoot())
+	_, err = merkletree.ReadTree(bytes.NewReader(snapshot), nil)
+	assert.EqualError(t, err, "please specify hash algo")
+}
+
+func FuzzReadTree(f *testing.F) {
+	for _, shape := range shapes {
+		tree, err := merkletree.NewTree(leaves(5), sha256, merkletree.WithShape(shape))
+		if err != nil {
+			f.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if _, err := tree.WriteTo(&buf); err != nil {
+			f.Fatal(err)
+		}
+		f.Add(buf.Bytes())
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// A snapshot that is read gives a tree writing the same snapshot.
+		tree, err := merkletree.ReadTree(bytes.NewReader(data), sha256)
+		if err != nil {
+			return
+		}
+		var buf bytes.Buffer
+		if _, err := tree.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, buf.Bytes()) {
+			t.Fatalf("snapshot %x written back as %x", data, buf.Bytes())
+		}
+	})
+}
@@ -0,0 +1,371 @@
+package merkletree
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/reactivejson/merkleTree/internal/merkle/hash"
+	"sort"
+)
+
+/**
+ * @author Mohamed-Aly Bou-Hanane
+ * © 2023
+ */
+
+// standardFormat is the format tag of the dump of a StandardMerkleTree.
+const standardFormat = "standard-v1"
+
+// StandardMerkleTree is a Merkle tree of ABI-encoded values, compatible with the StandardMerkleTree of OpenZeppelin's
+// @openzeppelin/merkle-tree library: for the same values and leaf encoding it has the same root, the same proofs and
+// the same JSON dump, and its proofs verify on-chain with OpenZeppelin's MerkleProof.verify and multiProofVerify.
+//
+// Each leaf is keccak256(keccak256(abi.encode(value...))), the leaves are sorted by hash, and every node is the
+// Keccak-256 hash of its two children in ascending order. Unlike MerkleTree, the nodes are laid out as a complete
+// binary tree: the root is at index 0, the children of node i are at 2i+1 and 2i+2, and the leaves fill the end of
+// the layout in reverse order, so no padding is needed.
+type StandardMerkleTree struct {
+	// tree holds the nodes in the layout of OpenZeppelin's library
+	tree [][]byte
+	// values are the values of the leaves, in the order they were given, with the index of their leaf in tree
+	values []standardValue
+	// leafEncoding holds the Solidity types of the fields of every value
+	leafEncoding []string
+	// hashLookup maps the hash of each leaf to the index of its value
+	hashLookup map[string]int
+}
+
+// standardValue is a value of a StandardMerkleTree and the index of its leaf, as found in the dump of the tree.
+type standardValue struct {
+	Value     []interface{} `json:"value"`
+	TreeIndex int           `json:"treeIndex"`
+}
+
+// standardTreeData is the dump of a StandardMerkleTree, with the fields in the order of OpenZeppelin's library.
+type standardTreeData struct {
+	Format       string          `json:"format"`
+	Tree         []string        `json:"tree"`
+	Values       []standardValue `json:"values"`
+	LeafEncoding []string        `json:"leafEncoding"`
+}
+
+// StandardMultiProof is a proof for several values of a StandardMerkleTree at once, in the format of
+// OpenZeppelin's MerkleProof.multiProofVerify.
+type StandardMultiProof struct {
+	Leaves     [][]interface{} // the proven values, in the order their leaves are consumed
+	Proof      [][]byte        // the sibling hashes that are not computed from the leaves
+	ProofFlags []bool          // for each node hashed, whether its second child is computed rather than taken from Proof
+}
+
+// standardHasher hashes the nodes of a StandardMerkleTree: Keccak-256 of the two children in ascending order.
+var standardHasher = newTreeHasher(hash.NewKeccak256(), DomainSeparation{}, ShapeZeroPad, true)
+
+// StandardLeafHash returns the hash of the leaf of a value in a StandardMerkleTree with the given leaf encoding,
+// keccak256(keccak256(abi.encode(value...))).
+func StandardLeafHash(leafEncoding []string, value []interface{}) ([]byte, error) {
+	encoded, err := abiEncode(leafEncoding, value)
+	if err != nil {
+		return nil, err
+	}
+	keccak := hash.NewKeccak256()
+	return keccak.Hash(keccak.Hash(encoded)), nil
+}
+
+// NewStandardTree creates a StandardMerkleTree of the values, each of them made of fields of the Solidity types of
+// leafEncoding, e.g. []string{"address", "uint256"}. See abiEncode for how the fields can be given.
+// values must contain at least one element for it to be valid.
+func NewStandardTree(values [][]interface{}, leafEncoding []string) (*StandardMerkleTree, error) {
+	if len(values) == 0 {
+		return nil, errors.New("the merkle tree should contains at least 1 piece of input")
+	}
+
+	type hashedValue struct {
+		valueIndex int
+		hash       []byte
+	}
+	hashed := make([]hashedValue, len(values))
+	for i, value := range values {
+		leaf, err := StandardLeafHash(leafEncoding, value)
+		if err != nil {
+			return nil, err
+		}
+		hashed[i] = hashedValue{valueIndex: i, hash: leaf}
+	}
+	sort.SliceStable(hashed, func(i, j int) bool { return bytes.Compare(hashed[i].hash, hashed[j].hash) < 0 })
+
+	t := &StandardMerkleTree{
+		tree:         make([][]byte, 2*len(values)-1),
+		values:       make([]standardValue, len(values)),
+		leafEncoding: append([]string(nil), leafEncoding...),
+	}
+	for leafIndex, h := range hashed {
+		treeIndex := len(t.tree) - 1 - leafIndex
+		t.tree[treeIndex] = h.hash
+		t.values[h.valueIndex] = standardValue{Value: values[h.valueIndex], TreeIndex: treeIndex}
+	}
+	for i := len(t.tree) - 1 - len(values); i >= 0; i-- {
+		t.tree[i] = standardHasher.node(t.tree[2*i+1], t.tree[2*i+2])
+	}
+	t.indexValues()
+
+	return t, nil
+}
+
+// indexValues rebuilds the lookup of leaf hashes to value indexes.
+func (t *StandardMerkleTree) indexValues() {
+	t.hashLookup = make(map[string]int, len(t.values))
+	for i, v := range t.values {
+		t.hashLookup[string(t.tree[v.TreeIndex])] = i
+	}
+}
+
+// LoadStandardTree loads a StandardMerkleTree from its dump, as written by Dump or by OpenZeppelin's library.
+// Every node is checked against the values, so a tree that doesn't match them is an error.
+func LoadStandardTree(dump []byte) (*StandardMerkleTree, error) {
+	var data standardTreeData
+	decoder := json.NewDecoder(bytes.NewReader(dump))
+	// Numbers are kept as they are written, so that big integers are neither rounded nor reformatted.
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	if data.Format != standardFormat {
+		return nil, fmt.Errorf("unknown format %q", data.Format)
+	}
+	if len(data.Values) == 0 || len(data.Tree) != 2*len(data.Values)-1 {
+		return nil, errors.New("the merkle tree does not match its values")
+	}
+
+	t := &StandardMerkleTree{
+		tree:         make([][]byte, len(data.Tree)),
+		values:       data.Values,
+		leafEncoding: data.LeafEncoding,
+	}
+	for i, node := range data.Tree {
+		b, err := abiBytes(node)
+		if err != nil || len(b) != 32 {
+			return nil, fmt.Errorf("invalid node %q", node)
+		}
+		t.tree[i] = b
+	}
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	t.indexValues()
+
+	return t, nil
+}
+
+// validate checks that every value is at a leaf holding its hash, and that every internal node is the hash of its children.
+func (t *StandardMerkleTree) validate() error {
+	firstLeaf := len(t.tree) / 2
+	for _, v := range t.values {
+		if v.TreeIndex < firstLeaf || v.TreeIndex >= len(t.tree) {
+			return errors.New("the merkle tree does not match its values")
+		}
+		leaf, err := StandardLeafHash(t.leafEncoding, v.Value)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(leaf, t.tree[v.TreeIndex]) {
+			return errors.New("the merkle tree does not match its values")
+		}
+	}
+	for i := firstLeaf - 1; i >= 0; i-- {
+		if !bytes.Equal(t.tree[i], standardHasher.node(t.tree[2*i+1], t.tree[2*i+2])) {
+			return errors.New("the merkle tree is invalid")
+		}
+	}
+	return nil
+}
+
+// Dump returns the JSON dump of the tree, byte for byte the same as JSON.stringify(tree.dump()) in OpenZeppelin's
+// library for the same values given as strings.
+func (t *StandardMerkleTree) Dump() ([]byte, error) {
+	data := standardTreeData{
+		Format:       standardFormat,
+		Tree:         make([]string, len(t.tree)),
+		Values:       t.values,
+		LeafEncoding: t.leafEncoding,
+	}
+	for i, node := range t.tree {
+		data.Tree[i] = "0x" + hex.EncodeToString(node)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	// JSON.stringify doesn't escape <, > and &.
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(data); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// MerkleRoot returns the root hash of the tree.
+func (t *StandardMerkleTree) MerkleRoot() []byte {
+	return t.tree[0]
+}
+
+// LeafEncoding returns the Solidity types of the fields of every value.
+func (t *StandardMerkleTree) LeafEncoding() []string {
+	return t.leafEncoding
+}
+
+// Len returns the number of values in the tree.
+func (t *StandardMerkleTree) Len() int {
+	return len(t.values)
+}
+
+// Value returns the value at the given index, in the order the values were given.
+func (t *StandardMerkleTree) Value(index int) []interface{} {
+	return t.values[index].Value
+}
+
+// LeafLookup returns the index of a value in the tree.
+func (t *StandardMerkleTree) LeafLookup(value []interface{}) (int, error) {
+	leaf, err := StandardLeafHash(t.leafEncoding, value)
+	if err != nil {
+		return 0, err
+	}
+	index, ok := t.hashLookup[string(leaf)]
+	if !ok {
+		return 0, errors.New("data not found")
+	}
+	return index, nil
+}
+
+// GenerateProof generates the proof for the value at the given index, the sibling hashes from the leaf up.
+func (t *StandardMerkleTree) GenerateProof(index int) ([][]byte, error) {
+	if index < 0 || index >= len(t.values) {
+		return nil, errors.New("index out of bounds")
+	}
+	proof := [][]byte{}
+	for i := t.values[index].TreeIndex; i > 0; i = (i - 1) / 2 {
+		// The sibling of an odd node is on its right, and the sibling of an even node on its left.
+		sibling := i + 1
+		if i%2 == 0 {
+			sibling = i - 1
+		}
+		proof = append(proof, t.tree[sibling])
+	}
+	return proof, nil
+}
+
+// GenerateMultiProof generates a single proof for the values at the given indexes, as OpenZeppelin's library does.
+// The leaves of the proof are the proven values, in the order multiProofVerify consumes them.
+func (t *StandardMerkleTree) GenerateMultiProof(indices []int) (*StandardMultiProof, error) {
+	stack := make([]int, len(indices))
+	for i, index := range indices {
+		if index < 0 || index >= len(t.values) {
+			return nil, errors.New("index out of bounds")
+		}
+		stack[i] = t.values[index].TreeIndex
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(stack)))
+	for i := 1; i < len(stack); i++ {
+		if stack[i] == stack[i-1] {
+			return nil, errors.New("duplicate index")
+		}
+	}
+
+	proof := &StandardMultiProof{
+		Leaves:     make([][]interface{}, len(stack)),
+		Proof:      [][]byte{},
+		ProofFlags: []bool{},
+	}
+	for i, treeIndex := range stack {
+		proof.Leaves[i] = t.values[t.hashLookup[string(t.tree[treeIndex])]].Value
+	}
+
+	// The nodes are consumed from the deepest and rightmost up, each one queueing its parent.
+	for len(stack) > 0 && stack[0] > 0 {
+		j := stack[0]
+		stack = stack[1:]
+		sibling := j + 1
+		if j%2 == 0 {
+			sibling = j - 1
+		}
+		if len(stack) > 0 && stack[0] == sibling {
+			proof.ProofFlags = append(proof.ProofFlags, true)
+			stack = stack[1:]
+		} else {
+			proof.ProofFlags = append(proof.ProofFlags, false)
+			proof.Proof = append(proof.Proof, t.tree[sibling])
+		}
+		stack = append(stack, (j-1)/2)
+	}
+	if len(indices) == 0 {
+		proof.Proof = append(proof.Proof, t.tree[0])
+	}
+	return proof, nil
+}
+
+// VerifyStandardProof verifies the proof of a value of a StandardMerkleTree against its root, as OpenZeppelin's
+// MerkleProof.verify does.
+//
+// This returns true if the proof is verified, otherwise false.
+func VerifyStandardProof(root []byte, leafEncoding []string, value []interface{}, proof [][]byte) (bool, error) {
+	node, err := StandardLeafHash(leafEncoding, value)
+	if err != nil {
+		return false, err
+	}
+	for _, sibling := range proof {
+		node = standardHasher.node(node, sibling)
+	}
+	return bytes.Equal(root, node), nil
+}
+
+// VerifyStandardMultiProof verifies a multiproof of values of a StandardMerkleTree against its root, as
+// OpenZeppelin's MerkleProof.multiProofVerify does.
+//
+// This returns true if the proof is verified, otherwise false. An error is returned for a malformed proof.
+func VerifyStandardMultiProof(root []byte, leafEncoding []string, proof *StandardMultiProof) (bool, error) {
+	if proof == nil {
+		return false, ErrNilProof
+	}
+	if len(proof.Leaves)+len(proof.Proof) != len(proof.ProofFlags)+1 {
+		return false, errors.New("the leaves and the proof are not compatible")
+	}
+
+	stack := make([][]byte, 0, len(proof.Leaves)+len(proof.ProofFlags))
+	for _, value := range proof.Leaves {
+		leaf, err := StandardLeafHash(leafEncoding, value)
+		if err != nil {
+			return false, err
+		}
+		stack = append(stack, leaf)
+	}
+	hashes := proof.Proof
+	for _, flag := range proof.ProofFlags {
+		if len(stack) == 0 {
+			return false, errors.New("not enough leaves in the proof")
+		}
+		a := stack[0]
+		stack = stack[1:]
+		var b []byte
+		switch {
+		case flag && len(stack) == 0:
+			return false, errors.New("not enough leaves in the proof")
+		case flag:
+			b, stack = stack[0], stack[1:]
+		case len(hashes) == 0:
+			return false, errors.New("not enough hashes in the proof")
+		default:
+			b, hashes = hashes[0], hashes[1:]
+		}
+		stack = append(stack, standardHasher.node(a, b))
+	}
+
+	// As in processMultiProof, every hash of the proof must have been used. The number of flags checked above already
+	// implies it once every flag is processed, so this only guards the loop against changes.
+	if len(proof.ProofFlags) > 0 && len(hashes) > 0 {
+		return false, errors.New("too many hashes in the proof")
+	}
+	if len(stack) > 0 {
+		return bytes.Equal(root, stack[len(stack)-1]), nil
+	}
+	return bytes.Equal(root, hashes[0]), nil
+}
@@ -0,0 +1,268 @@
+package merkletree_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	merkletree "github.com/reactivejson/merkleTree/internal/merkle"
+	"github.com/reactivejson/merkleTree/internal/merkle/hash"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var airdropEncoding = []string{"address", "uint256"}
+
+var airdropValues = [][]interface{}{
+	{"0x1111111111111111111111111111111111111111", "5000000000000000000"},
+	{"0x2222222222222222222222222222222222222222", "2500000000000000000"},
+}
+
+// The root, proof and dump of @openzeppelin/merkle-tree for the example of its README.
+const airdropDump = `{"format":"standard-v1","tree":["0xd4dee0beab2d53f2cc83e567171bd2820e49898130a22622b10ead383e90bd77","0xeb02c421cfa48976e66dfb29120745909ea3a0f843456c263cf8f1253483e283","0xb92c48e9d7abe27fd8dfd6b5dfdbfb1c9a463f80c712b66f3a5180a090cccafc"],"values":[{"value":["0x1111111111111111111111111111111111111111","5000000000000000000"],"treeIndex":1},{"value":["0x2222222222222222222222222222222222222222","2500000000000000000"],"treeIndex":2}],"leafEncoding":["address","uint256"]}`
+
+func hexBytes(s string) []byte {
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestStandardTree(t *testing.T) {
+	tree, err := merkletree.NewStandardTree(airdropValues, airdropEncoding)
+	assert.NoError(t, err)
+	assert.Equal(t, hexBytes("0xd4dee0beab2d53f2cc83e567171bd2820e49898130a22622b10ead383e90bd77"), tree.MerkleRoot())
+
+	proof, err := tree.GenerateProof(0)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{hexBytes("0xb92c48e9d7abe27fd8dfd6b5dfdbfb1c9a463f80c712b66f3a5180a090cccafc")}, proof)
+
+	dump, err := tree.Dump()
+	assert.NoError(t, err)
+	assert.Equal(t, airdropDump, string(dump))
+
+	index, err := tree.LeafLookup(airdropValues[1])
+	assert.NoError(t, err)
+	assert.Equal(t, 1, index)
+	_, err = tree.LeafLookup([]interface{}{"0x3333333333333333333333333333333333333333", "1"})
+	assert.EqualError(t, err, "data not found")
+
+	for i, value := range airdropValues {
+		proof, err := tree.GenerateProof(i)
+		assert.NoError(t, err)
+		verified, err := merkletree.VerifyStandardProof(tree.MerkleRoot(), airdropEncoding, value, proof)
+		assert.NoError(t, err)
+		assert.True(t, verified, fmt.Sprintf("failed to verify proof at test %d", i))
+		verified, err = merkletree.VerifyStandardProof(tree.MerkleRoot(), airdropEncoding, airdropValues[1-i], proof)
+		assert.NoError(t, err)
+		assert.False(t, verified, fmt.Sprintf("verified wrong value at test %d", i))
+	}
+
+	_, err = merkletree.NewStandardTree(nil, airdropEncoding)
+	assert.Error(t, err)
+	_, err = tree.GenerateProof(2)
+	assert.EqualError(t, err, "index out of bounds")
+}
+
+func TestStandardTreeLoad(t *testing.T) {
+	tree, err := merkletree.LoadStandardTree([]byte(airdropDump))
+	assert.NoError(t, err)
+	assert.Equal(t, hexBytes("0xd4dee0beab2d53f2cc83e567171bd2820e49898130a22622b10ead383e90bd77"), tree.MerkleRoot())
+	assert.Equal(t, 2, tree.Len())
+	assert.Equal(t, airdropEncoding, tree.LeafEncoding())
+
+	dump, err := tree.Dump()
+	assert.NoError(t, err)
+	assert.Equal(t, airdropDump, string(dump))
+
+	tests := []struct {
+		dump string
+		err  string
+	}{
+		{ // 0
+			dump: strings.Replace(airdropDump, "standard-v1", "standard-v2", 1),
+			err:  `unknown format "standard-v2"`,
+		},
+		{ // 1
+			dump: strings.Replace(airdropDump, "5000000000000000000", "5000000000000000001", 1),
+			err:  "the merkle tree does not match its values",
+		},
+		{ // 2
+			dump: strings.Replace(airdropDump, "0xd4de", "0xd4df", 1),
+			err:  "the merkle tree is invalid",
+		},
+		{ // 3
+			dump: strings.Replace(airdropDump, `"treeIndex":1`, `"treeIndex":0`, 1),
+			err:  "the merkle tree does not match its values",
+		},
+		{ // 4
+			dump: strings.Replace(airdropDump, "0xd4de", "0xd4", 1),
+			err:  `invalid node "0xd4e0beab2d53f2cc83e567171bd2820e49898130a22622b10ead383e90bd77"`,
+		},
+	}
+
+	for i, test := range tests {
+		_, err := merkletree.LoadStandardTree([]byte(test.dump))
+		assert.EqualError(t, err, test.err, fmt.Sprintf("unexpected error at test %d", i))
+	}
+}
+
+func TestStandardMultiProof(t *testing.T) {
+	values := make([][]interface{}, 7)
+	for i := range values {
+		values[i] = []interface{}{fmt.Sprintf("0x%040x", i+1), fmt.Sprint(1000 * (i + 1))}
+	}
+	tree, err := merkletree.NewStandardTree(values, airdropEncoding)
+	assert.NoError(t, err)
+	root := tree.MerkleRoot()
+
+	tests := [][]int{{0}, {6}, {1, 2}, {0, 3, 5}, {6, 5, 4, 3, 2, 1, 0}, {}}
+	for i, indices := range tests {
+		proof, err := tree.GenerateMultiProof(indices)
+		assert.NoError(t, err)
+		assert.Len(t, proof.Leaves, len(indices))
+		verified, err := merkletree.VerifyStandardMultiProof(root, airdropEncoding, proof)
+		assert.NoError(t, err)
+		assert.True(t, verified, fmt.Sprintf("failed to verify multiproof at test %d", i))
+
+		if len(proof.Leaves) > 0 {
+			proof.Leaves[0] = []interface{}{"0x0000000000000000000000000000000000000009", "1"}
+			verified, err = merkletree.VerifyStandardMultiProof(root, airdropEncoding, proof)
+			assert.NoError(t, err)
+			assert.False(t, verified, fmt.Sprintf("verified wrong value at test %d", i))
+		}
+	}
+
+	_, err = tree.GenerateMultiProof([]int{1, 1})
+	assert.EqualError(t, err, "duplicate index")
+	_, err = tree.GenerateMultiProof([]int{7})
+	assert.EqualError(t, err, "index out of bounds")
+
+	proof, err := tree.GenerateMultiProof([]int{0, 3})
+	assert.NoError(t, err)
+	flags := proof.ProofFlags
+	proof.ProofFlags = flags[1:]
+	_, err = merkletree.VerifyStandardMultiProof(root, airdropEncoding, proof)
+	assert.EqualError(t, err, "the leaves and the proof are not compatible")
+
+	// Surplus hashes are rejected rather than ignored.
+	proof.ProofFlags = flags
+	proof.Proof = append(proof.Proof, root)
+	verified, err := merkletree.VerifyStandardMultiProof(root, airdropEncoding, proof)
+	assert.EqualError(t, err, "the leaves and the proof are not compatible")
+	assert.False(t, verified)
+
+	_, err = merkletree.VerifyStandardMultiProof(root, airdropEncoding, nil)
+	assert.ErrorIs(t, err, merkletree.ErrNilProof)
+}
+
+func TestStandardLeafHash(t *testing.T) {
+	keccak := hash.NewKeccak256()
+	word := func(s string) string {
+		return strings.Repeat("0", 64-len(s)) + s
+	}
+
+	tests := []struct {
+		types   []string
+		value   []interface{}
+		encoded string
+		err     string
+	}{
+		{ // 0
+			types:   []string{"bool", "uint8", "int256"},
+			value:   []interface{}{true, 255, "-1"},
+			encoded: word("1") + word("ff") + strings.Repeat("f", 64),
+		},
+		{ // 1
+			types:   []string{"bytes4", "uint64"},
+			value:   []interface{}{"0xdeadbeef", "0x10"},
+			encoded: "deadbeef" + strings.Repeat("0", 56) + word("10"),
+		},
+		{ // 2
+			types:   []string{"string", "uint256", "bytes"},
+			value:   []interface{}{"hello", "1", []byte{1, 2}},
+			encoded: word("60") + word("1") + word("a0") + word("5") + "68656c6c6f" + strings.Repeat("0", 54) + word("2") + "0102" + strings.Repeat("0", 60),
+		},
+		{ // 3
+			types: []string{"uint8"},
+			value: []interface{}{256},
+			err:   "uint8 value 256 out of range",
+		},
+		{ // 4
+			types: []string{"address"},
+			value: []interface{}{"0x1234"},
+			err:   "invalid address value 0x1234",
+		},
+		{ // 5
+			types: []string{"uint7"},
+			value: []interface{}{"1"},
+			err:   "unsupported type uint7",
+		},
+		{ // 6
+			types: []string{"address", "uint256"},
+			value: []interface{}{"0x1111111111111111111111111111111111111111"},
+			err:   "expected 2 values for the leaf encoding, got 1",
+		},
+	}
+
+	for i, test := range tests {
+		leaf, err := merkletree.StandardLeafHash(test.types, test.value)
+		if test.err != "" {
+			assert.EqualError(t, err, test.err, fmt.Sprintf("unexpected error at test %d", i))
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, keccak.Hash(keccak.Hash(hexBytes(test.encoded))), leaf, fmt.Sprintf("unexpected leaf hash at test %d", i))
+	}
+}
+
+func TestSortedPairs(t *testing.T) {
+	keccak := hash.NewKeccak256()
+	values := make([][]byte, 4)
+	for i := range values {
+		values[i] = keccak.Hash([]byte{byte(i)})
+	}
+	// OpenZeppelin sorts the leaves by hash.
+	leafHash := func(value []byte) []byte { return keccak.Hash(keccak.Hash(value)) }
+	sort.Slice(values, func(i, j int) bool { return bytes.Compare(leafHash(values[i]), leafHash(values[j])) < 0 })
+
+	standardValues := make([][]interface{}, len(values))
+	data := make([][]byte, len(values))
+	for i, value := range values {
+		standardValues[i] = []interface{}{value}
+		// The generic tree hashes its data once, and OpenZeppelin's leaves are hashed twice.
+		data[i] = keccak.Hash(value)
+	}
+	standard, err := merkletree.NewStandardTree(standardValues, []string{"bytes32"})
+	assert.NoError(t, err)
+
+	tree, err := merkletree.NewTree(data, keccak, merkletree.WithSortedPairs())
+	assert.NoError(t, err)
+	assert.Equal(t, standard.MerkleRoot(), tree.MerkleRoot())
+	assert.True(t, tree.Params().SortedPairs)
+
+	unsorted, err := merkletree.NewTree(data, keccak)
+	assert.NoError(t, err)
+	assert.NotEqual(t, standard.MerkleRoot(), unsorted.MerkleRoot())
+
+	for i, d := range data {
+		proof, err := tree.GenerateMProofAt(uint64(i))
+		assert.NoError(t, err)
+		verified, err := merkletree.VerifyMProof(d, proof, tree.MerkleRoot(), keccak, merkletree.WithSortedPairs())
+		assert.NoError(t, err)
+		assert.True(t, verified, fmt.Sprintf("failed to verify proof at test %d", i))
+		_, err = merkletree.VerifyMProof(d, proof, tree.MerkleRoot(), keccak)
+		assert.EqualError(t, err, "the proof was built with another pair ordering", fmt.Sprintf("unexpected error at test %d", i))
+
+		// The proofs of both trees hold the same hashes, so either tree's proof verifies against the other's root.
+		standardProof, err := standard.GenerateProof(i)
+		assert.NoError(t, err)
+		assert.Equal(t, standardProof, proof.Hashes, fmt.Sprintf("unexpected proof at test %d", i))
+		verified, err = merkletree.VerifyStandardProof(tree.MerkleRoot(), []string{"bytes32"}, standardValues[i], proof.Hashes)
+		assert.NoError(t, err)
+		assert.True(t, verified, fmt.Sprintf("failed to verify generic proof at test %d", i))
+	}
+}
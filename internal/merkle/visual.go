@@ -28,15 +28,13 @@ func (t *MerkleTree) VisualProof(proof *MerkleProof, lf Formatter, bf Formatter)
 		index := proof.Index + uint64(math.Ceil(float64(len(t.nodes))/2))
 		valueIndices[proof.Index] = 1
 
-		for range proof.Hashes {
-			proofIndices[index^1] = 1
-			index /= 2
-		}
-
-		numRootNodes := uint64(math.Exp2(math.Ceil(math.Log2(float64(len(t.data))))-float64(len(proof.Hashes))+1)) - 1
-		for i := uint64(1); i <= numRootNodes; i++ {
-			rootIndices[i] = 1
+		// Walk up to the root, skipping the levels where the node has no sibling.
+		for ; index > 1; index /= 2 {
+			if t.nodes[index^1] != nil {
+				proofIndices[index^1] = 1
+			}
 		}
+		rootIndices[1] = 1
 	}
 
 	return t.visual(rootIndices, valueIndices, proofIndices, lf, bf)
@@ -80,7 +78,7 @@ func (t *MerkleTree) visual(rootIndices, valueIndices, proofIndices map[uint64]i
 			if i > 0 {
 				builder.WriteString(fmt.Sprintf("%d->%d [style=invisible arrowhead=none];", valuesOffset+i-1, valuesOffset+i))
 			}
-		} else {
+		} else if t.nodes[valuesOffset+i] != nil {
 			// Empty leaf
 			builder.WriteString(fmt.Sprintf("%d [label=\"%s\"", valuesOffset+i, bf.Format(empty)))
 			if proofIndices[uint64(i+valuesOffset)] > 0 {
@@ -91,6 +89,9 @@ func (t *MerkleTree) visual(rootIndices, valueIndices, proofIndices map[uint64]i
 			builder.WriteString("];")
 			builder.WriteString(fmt.Sprintf("%d->%d [style=invisible arrowhead=none];", valuesOffset+i-1, valuesOffset+i))
 			nodeBuilder.WriteString(fmt.Sprintf(";%d", valuesOffset+i))
+		} else {
+			// No leaf, the shape of the tree completes odd levels without padding.
+			continue
 		}
 		if dataLen > 1 {
 			builder.WriteString(fmt.Sprintf("%d->%d;", valuesOffset+i, (valuesOffset+i)/2))
@@ -101,6 +102,9 @@ func (t *MerkleTree) visual(rootIndices, valueIndices, proofIndices map[uint64]i
 
 	// Add branches
 	for i := valuesOffset - 1; i > 0; i-- {
+		if t.nodes[i] == nil {
+			continue
+		}
 		builder.WriteString(fmt.Sprintf("%d [label=\"%s\"", i, bf.Format(t.nodes[i])))
 		if rootIndices[uint64(i)] > 0 {
 			builder.WriteString(" style=filled fillcolor=\"#C0C0C0\"")